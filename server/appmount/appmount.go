@@ -7,11 +7,10 @@ import (
 
 	"github.com/alexmspina/worldmap/server/helpers"
 	"github.com/alexmspina/worldmap/server/models"
-	satellite "github.com/joshuaferrara/go-satellite"
 )
 
-// AppMount initializes app state
-func AppMount(t <-chan time.Time, dir *string) {
+// AppMount initializes app state, then propagates satellites on every tick of t and reloads changed data files on every tick of poll
+func AppMount(t <-chan time.Time, dir *string, poll <-chan time.Time) {
 	fmt.Println("Mounting application")
 
 	// use Walk function to traverse root directory provided and create a list of files
@@ -20,15 +19,14 @@ func AppMount(t <-chan time.Time, dir *string) {
 
 	// Create map of regular expressions
 	regexmap := make(map[string]*regexp.Regexp, 0)
-	preregexlist := []string{"TARGETS", "BEAMPLAN_LONGFORMAT", "ZONES", "ephemeris"}
+	preregexlist := []string{"TARGETS", "BEAMPLAN_LONGFORMAT", "ZONES", "ROSTER", "ephemeris", "OMM", "OEM"}
 	helpers.CreateRegexp(regexmap, preregexlist)
 
-	bpregexmap := make(map[string]*regexp.Regexp, 0)
-	bppreregex := []string{"mute", "B3", "M001", "M013"}
-	helpers.CreateRegexp(bpregexmap, bppreregex)
-
 	bpfilelist := make(map[string]string, 0)
-	models.GetBeamplanFiles(files, regexmap["BEAMPLAN_LONGFORMAT"], bpregexmap, bpfilelist)
+	models.GetBeamplanFiles(files, regexmap["BEAMPLAN_LONGFORMAT"], bpfilelist)
+
+	// record data file checksums before ingesting so changes made during startup are picked up
+	watcher := NewDataWatcher(*dir, regexmap)
 
 	fmt.Println("Building data models")
 
@@ -36,18 +34,31 @@ func AppMount(t <-chan time.Time, dir *string) {
 	models.ProcessInitFiles(files, regexmap)
 
 	// Process files if they are tles
-	sgp4sats := models.ProcessEphemeris(files, regexmap, bpfilelist)
+	orbits, err := models.ProcessEphemeris(files, regexmap, bpfilelist)
+	if err != nil {
+		fmt.Println("Could not load the fleet:", err)
+		orbits = models.InitSatelliteOrbits(models.GetSatelliteStates(), time.Now())
+	}
+	models.LiveSatellites.Store(orbits)
+
+	// watch the data directory for new or changed files
+	go watcher.Watch(poll)
 
+	// reselect the archived tle closest to now every hour so live positions follow the nearest epoch
+	selectedAt := time.Now()
 	for {
 		select {
-		case <-t:
-			currentTime := <-t
-			models.UpdateSatPos(currentTime, sgp4sats)
+		case currentTime := <-t:
+			if currentTime.Sub(selectedAt) >= time.Hour {
+				models.LiveSatellites.Store(models.InitSatelliteOrbits(models.GetSatelliteStates(), currentTime))
+				selectedAt = currentTime
+			}
+			models.UpdateSatPos(currentTime, models.LiveSatellites.Load())
 		}
 	}
 }
 
 // AppTicker global ticker for entire app
-func AppTicker(ticker <-chan *time.Ticker, sgp4sats map[string]satellite.Satellite) {
+func AppTicker(ticker <-chan *time.Ticker, orbits map[string]models.Orbit) {
 
 }
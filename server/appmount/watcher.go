@@ -0,0 +1,161 @@
+package appmount
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/alexmspina/worldmap/server/models"
+)
+
+// DataWatcher polls the data directory and re-ingests the buckets whose files appear or change
+type DataWatcher struct {
+	dir       string
+	regexmap  map[string]*regexp.Regexp
+	checksums map[string]string
+}
+
+// NewDataWatcher creates a watcher primed with the current checksums of the data directory
+func NewDataWatcher(dir string, regexmap map[string]*regexp.Regexp) *DataWatcher {
+	w := &DataWatcher{
+		dir:      dir,
+		regexmap: regexmap,
+	}
+	w.checksums = w.scan()
+	return w
+}
+
+// Watch checks the data directory on every tick until the channel closes
+func (w *DataWatcher) Watch(t <-chan time.Time) {
+	for range t {
+		w.safeReload()
+	}
+}
+
+// safeReload keeps an unexpected panic in an ingest path from taking down the server while it is running. Files that
+// cannot be read are reported by the ingest functions as errors and leave their buckets unchanged
+func (w *DataWatcher) safeReload() {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Println("Could not reload data directory:", err)
+		}
+	}()
+	w.Reload()
+}
+
+// Reload re-ingests every category of data file that was added, changed or removed since the last check
+func (w *DataWatcher) Reload() {
+	checksums := w.scan()
+
+	changed := make(map[string]bool, 0)
+	for file, sum := range checksums {
+		if w.checksums[file] != sum {
+			w.markChanged(file, changed)
+		}
+	}
+	for file := range w.checksums {
+		if _, ok := checksums[file]; !ok {
+			w.markChanged(file, changed)
+		}
+	}
+	w.checksums = checksums
+	if len(changed) == 0 {
+		return
+	}
+
+	files := make([]string, 0)
+	for file := range checksums {
+		files = append(files, file)
+	}
+	fmt.Println("Data directory changed, reloading", changed)
+
+	if changed["TARGETS"] && w.hasFiles(files, "TARGETS") {
+		w.report("targets", models.FillTargetsBucket(w.matching(files, "TARGETS")))
+	}
+	if changed["ZONES"] && w.hasFiles(files, "ZONES") {
+		w.report("zones", models.FillZonesBucket(w.matching(files, "ZONES")))
+	}
+	if changed["ROSTER"] && w.hasFiles(files, "ROSTER") {
+		w.report("roster", models.FillRosterBucket(w.matching(files, "ROSTER")))
+	}
+	if (changed["ROSTER"] || changed["BEAMPLAN_LONGFORMAT"] || changed["ephemeris"] || changed["OMM"] || changed["OEM"]) && (w.hasFiles(files, "ephemeris") || w.hasFiles(files, "OMM")) {
+		bpfilelist := make(map[string]string, 0)
+		models.GetBeamplanFiles(files, w.regexmap["BEAMPLAN_LONGFORMAT"], bpfilelist)
+
+		orbits, err := models.ProcessEphemeris(files, w.regexmap, bpfilelist)
+		w.report("fleet", err)
+		if err == nil {
+			models.LiveSatellites.Store(orbits)
+			models.PruneSatPosBucket(orbits)
+		}
+	}
+	fmt.Println("Reload done")
+}
+
+// scan checksums every data file in the directory
+func (w *DataWatcher) scan() map[string]string {
+	files := make([]string, 0)
+	helpers.GetFilesFromDirectory(&files, w.dir)
+
+	checksums := make(map[string]string, 0)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() || len(w.categories(file)) == 0 {
+			continue
+		}
+		sum, err := helpers.ChecksumFile(file)
+		if err != nil {
+			continue
+		}
+		checksums[file] = sum
+	}
+
+	return checksums
+}
+
+// categories returns the regular expression keys the file name matches
+func (w *DataWatcher) categories(file string) []string {
+	keys := make([]string, 0)
+	for key, regex := range w.regexmap {
+		if regex.MatchString(filepath.Base(file)) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (w *DataWatcher) markChanged(file string, changed map[string]bool) {
+	for _, key := range w.categories(file) {
+		changed[key] = true
+	}
+}
+
+func (w *DataWatcher) hasFiles(files []string, key string) bool {
+	for _, file := range files {
+		if w.regexmap[key].MatchString(filepath.Base(file)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns the files whose names match the regular expression of key
+func (w *DataWatcher) matching(files []string, key string) []string {
+	matched := make([]string, 0)
+	for _, file := range files {
+		if w.regexmap[key].MatchString(filepath.Base(file)) {
+			matched = append(matched, file)
+		}
+	}
+	return matched
+}
+
+// report logs a failed reload, whose bucket keeps its previous contents
+func (w *DataWatcher) report(name string, err error) {
+	if err != nil {
+		fmt.Println("Could not reload", name, "keeping the previous data:", err)
+	}
+}
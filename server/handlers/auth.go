@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuthToken bearer token clients must send to change data, every change is refused while it is empty
+var AuthToken string
+
+// AllowedOrigins browser origins other than the served host that may send changes and open subscriptions
+var AllowedOrigins []string
+
+// authorized reports whether the request carries the server's bearer token
+func authorized(r *http.Request) bool {
+	if AuthToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(AuthToken)) == 1
+}
+
+// originAllowed reports whether a request comes from a page on the served host or an allowed origin.
+// Requests without an Origin header do not come from a browser page and are left to the token check
+func originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host) || helpers.StringInSlice(origin, AllowedOrigins)
+}
+
+// AllowOrigins answers cors requests from the served host and AllowedOrigins only, for endpoints that change data
+func AllowOrigins(h http.Handler) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !originAllowed(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Content-Length, Accept-Encoding")
+		if r.Method == "OPTIONS" {
+			w.Header().Set("Access-Control-Max-Age", "86400")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+	})
+}
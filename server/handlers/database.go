@@ -22,7 +22,7 @@ func (dbhandler *DBHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // GetStuff2 another getstuff function for testing passing db to handler
 func GetStuff2(db *bolt.DB) httprouter.Handle {
-	sats := []string{"M001", "M002", "M003", "M004", "M005", "M006", "M007", "M008", "M009", "M010", "M011", "M012", "M013", "M014", "M015", "M016"}
+	sats := models.GetRosterIDs()
 	bytes := make([]byte, 0)
 
 	for _, s := range sats {
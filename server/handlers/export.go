@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/models"
+)
+
+// getWindowParams parses the RFC3339 start and end and the step in seconds from the url query
+func getWindowParams(r *http.Request, defaultStep int) (time.Time, time.Time, time.Duration, error) {
+	query := r.URL.Query()
+	start, err := time.Parse(time.RFC3339, query.Get("start"))
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("start must be an RFC3339 time: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, query.Get("end"))
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("end must be an RFC3339 time: %v", err)
+	}
+	step := defaultStep
+	if s := query.Get("step"); s != "" {
+		step, err = strconv.Atoi(s)
+		if err != nil || step <= 0 {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("step must be a positive number of seconds")
+		}
+	}
+
+	return start, end, time.Duration(step) * time.Second, nil
+}
+
+// LinkSeriesCSVHandlerFunc exports doppler and link geometry between a target and a satellite as csv
+func LinkSeriesCSVHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	start, end, step, err := getWindowParams(r, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	frequency, err := strconv.ParseFloat(query.Get("frequency"), 64)
+	if err != nil {
+		http.Error(w, "frequency must be a number of hertz", http.StatusBadRequest)
+		return
+	}
+
+	samples, err := models.BuildLinkSeries(query.Get("targetId"), query.Get("satelliteId"), start, end, step, frequency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := models.WriteLinkSeriesCSV(&body, samples); err != nil {
+		http.Error(w, fmt.Sprintf("could not write link series csv: %v", err), http.StatusInternalServerError)
+		return
+	}
+	filename := fmt.Sprintf("link_%s_%s.csv", query.Get("targetId"), query.Get("satelliteId"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeBody(w, body.Bytes(), filename)
+}
+
+// writeBody sends an export that was rendered in full, logging a failed write since the status has already been sent
+func writeBody(w http.ResponseWriter, body []byte, name string) {
+	if _, err := w.Write(body); err != nil {
+		log.Println("could not send", name, ":", err)
+	}
+}
+
+// CZMLHandlerFunc exports the fleet sampled over a time window, the targets and the catseyes as a CZML document for Cesium
+func CZMLHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	start, end, step, err := getWindowParams(r, 60)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packets, err := models.BuildCZML(start, end, step)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(packets)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not write czml: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="worldmap.czml"`)
+	writeBody(w, body, "worldmap.czml")
+}
+
+// KMLHandlerFunc exports the targets and catseyes as KML for Google Earth, with satellite ground tracks when a start and end are given.
+// format=kmz zips the document as KMZ
+func KMLHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var start, end time.Time
+	var step time.Duration
+	tracks := query.Get("start") != "" || query.Get("end") != ""
+	if tracks {
+		var err error
+		start, end, step, err = getWindowParams(r, 60)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	kml, err := models.BuildKML(start, end, step, tracks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var body bytes.Buffer
+	var filename, contentType string
+	switch query.Get("format") {
+	case "", "kml":
+		filename, contentType = "worldmap.kml", "application/vnd.google-earth.kml+xml"
+		err = models.WriteKML(&body, kml)
+	case "kmz":
+		filename, contentType = "worldmap.kmz", "application/vnd.google-earth.kmz"
+		err = models.WriteKMZ(&body, kml)
+	default:
+		http.Error(w, "format must be kml or kmz", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not write %s: %v", filename, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeBody(w, body.Bytes(), filename)
+}
+
+// GeoJSONHandler serves a layer as an RFC 7946 feature collection. bbox=west,south,east,north keeps features intersecting the box,
+// every other query parameter keeps features whose property of that name equals one of its values. Query parameters that
+// name no property of the layer are rejected
+func GeoJSONHandler(layer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		features, ok := models.GeoJSONLayers[layer]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown layer %s", layer), http.StatusNotFound)
+			return
+		}
+
+		filters := r.URL.Query()
+		var bbox []float64
+		if s := filters.Get("bbox"); s != "" {
+			var err error
+			bbox, err = models.ParseBBox(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		filters.Del("bbox")
+		if err := models.ValidateGeoJSONFilters(layer, filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		collection := models.NewGeoJSONFeatureCollection(models.FilterGeoJSONFeatures(features(), bbox, filters))
+		w.Header().Set("Content-Type", "application/geo+json")
+		json.NewEncoder(w).Encode(collection)
+	}
+}
@@ -26,6 +26,15 @@ func DisableCors(h http.Handler) httprouter.Handle {
 	})
 }
 
+// DisableCorsHandle disables cors filtered requests for an httprouter handle that reads its route params
+func DisableCorsHandle(h httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		DisableCors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h(w, r, ps)
+		}))(w, r, ps)
+	})
+}
+
 // StringKey creates a special type so it doesn't conflict with standard strings
 type StringKey string
 
@@ -10,7 +10,7 @@ import (
 	"github.com/alexmspina/worldmap/server/models"
 )
 
-// GraphqlHandlerFunc handler func that uses graphql-go handler
+// GraphqlHandlerFunc handler func that uses graphql-go handler. Mutations run only for requests carrying AuthToken
 func GraphqlHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	// get query
 	opts := handler.NewRequestOptions(r)
@@ -21,7 +21,7 @@ func GraphqlHandlerFunc(w http.ResponseWriter, r *http.Request) {
 		RequestString:  opts.Query,
 		VariableValues: opts.Variables,
 		OperationName:  opts.OperationName,
-		Context:        r.Context(),
+		Context:        models.WithAuthorization(r.Context(), authorized(r)),
 	}
 
 	result := models.ExecuteQuery(r.URL.Query().Get("query"), params)
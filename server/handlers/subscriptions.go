@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/alexmspina/worldmap/server/models"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/julienschmidt/httprouter"
+)
+
+// graphql-ws message types used by apollo subscriptions-transport-ws clients
+const (
+	gqlConnectionInit      = "connection_init"
+	gqlConnectionAck       = "connection_ack"
+	gqlConnectionError     = "connection_error"
+	gqlConnectionTerminate = "connection_terminate"
+	gqlStart               = "start"
+	gqlStop                = "stop"
+	gqlData                = "data"
+	gqlError               = "error"
+	gqlComplete            = "complete"
+)
+
+// operationMessage envelope for every graphql-ws message in both directions
+type operationMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// startPayload graphql request carried by a start message
+type startPayload struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// upgrader accepts websockets opened from pages on the served host or an AllowedOrigins origin
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{"graphql-ws"},
+	CheckOrigin:  originAllowed,
+}
+
+// subscriptionConnection single websocket client and its running subscriptions
+type subscriptionConnection struct {
+	ws         *websocket.Conn
+	writeMutex sync.Mutex
+	opsMutex   sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+// SubscriptionsHandler upgrades the request to a websocket and serves graphql subscriptions over the graphql-ws protocol
+func SubscriptionsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("could not upgrade subscription connection:", err)
+		return
+	}
+
+	conn := &subscriptionConnection{
+		ws:         ws,
+		operations: make(map[string]context.CancelFunc, 0),
+	}
+	defer conn.close()
+
+	for {
+		var msg operationMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case gqlConnectionInit:
+			conn.send(operationMessage{Type: gqlConnectionAck})
+		case gqlStart:
+			var payload startPayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				conn.sendError(msg.ID, err)
+				continue
+			}
+			conn.start(msg.ID, payload)
+		case gqlStop:
+			conn.stop(msg.ID)
+		case gqlConnectionTerminate:
+			return
+		default:
+			conn.send(operationMessage{Type: gqlConnectionError})
+		}
+	}
+}
+
+// start runs a subscription and streams its results until it is stopped or the client leaves
+func (c *subscriptionConnection) start(id string, payload startPayload) {
+	c.stop(id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.opsMutex.Lock()
+	c.operations[id] = cancel
+	c.opsMutex.Unlock()
+
+	results := graphql.Subscribe(graphql.Params{
+		Schema:         models.Schema,
+		RequestString:  payload.Query,
+		VariableValues: payload.Variables,
+		OperationName:  payload.OperationName,
+		Context:        ctx,
+	})
+
+	go func() {
+		for result := range results {
+			resultBytes, err := json.Marshal(result)
+			if err != nil {
+				c.sendError(id, err)
+				continue
+			}
+			c.send(operationMessage{ID: id, Type: gqlData, Payload: resultBytes})
+		}
+		c.send(operationMessage{ID: id, Type: gqlComplete})
+	}()
+}
+
+// stop cancels a running subscription
+func (c *subscriptionConnection) stop(id string) {
+	c.opsMutex.Lock()
+	if cancel, ok := c.operations[id]; ok {
+		cancel()
+		delete(c.operations, id)
+	}
+	c.opsMutex.Unlock()
+}
+
+// close cancels every running subscription and closes the websocket
+func (c *subscriptionConnection) close() {
+	c.opsMutex.Lock()
+	for id, cancel := range c.operations {
+		cancel()
+		delete(c.operations, id)
+	}
+	c.opsMutex.Unlock()
+	c.ws.Close()
+}
+
+func (c *subscriptionConnection) send(msg operationMessage) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.ws.WriteJSON(msg)
+}
+
+func (c *subscriptionConnection) sendError(id string, err error) {
+	errorBytes, _ := json.Marshal(map[string]string{"message": err.Error()})
+	c.send(operationMessage{ID: id, Type: gqlError, Payload: errorBytes})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alexmspina/worldmap/server/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// TileHandler serves /tiles/:layer/:z/:x/:y.mvt as a mapbox vector tile encoded from the layer's bucket
+func TileHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !strings.HasSuffix(ps.ByName("y"), ".mvt") {
+		http.NotFound(w, r)
+		return
+	}
+	z, errz := strconv.Atoi(ps.ByName("z"))
+	x, errx := strconv.Atoi(ps.ByName("x"))
+	y, erry := strconv.Atoi(strings.TrimSuffix(ps.ByName("y"), ".mvt"))
+	if errz != nil || errx != nil || erry != nil {
+		http.Error(w, "z, x and y must be integers", http.StatusBadRequest)
+		return
+	}
+	if _, ok := models.TileLayers[ps.ByName("layer")]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	tile, err := models.BuildTile(ps.ByName("layer"), z, x, y)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Write(tile)
+}
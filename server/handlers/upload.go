@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/alexmspina/worldmap/server/models"
+)
+
+// maxUploadSize upper bound on uploaded data files
+const maxUploadSize = 32 << 20
+
+// readUploadedFile reads the named file field of a multipart form
+func readUploadedFile(r *http.Request, field string) ([]byte, error) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, err
+	}
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
+
+// UploadTLEHandlerFunc accepts a 3 line element set or CCSDS OMM as the "file" field of a multipart form,
+// from requests carrying AuthToken only
+func UploadTLEHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		http.Error(w, "uploads need the server's token as a bearer Authorization header", http.StatusUnauthorized)
+		return
+	}
+
+	text, err := readUploadedFile(r, "file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := models.UploadTLE(string(text))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		log.Println("could not send upload result:", err)
+	}
+}
@@ -2,8 +2,11 @@ package helpers
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
@@ -30,6 +33,22 @@ func GetFilesFromDirectory(f *[]string, d string) {
 	PanicErrors(err)
 }
 
+// ChecksumFile returns the hex encoded sha256 checksum of a file's contents
+func ChecksumFile(f string) (string, error) {
+	file, err := os.Open(f)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // AppendBytes adds a byte slice to another by byte
 func AppendBytes(mainslice *[]byte, addingslice []byte) {
 	for _, i := range addingslice {
@@ -4,10 +4,12 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/alexmspina/worldmap/server/appmount"
 	"github.com/alexmspina/worldmap/server/handlers"
+	"github.com/alexmspina/worldmap/server/models"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -15,16 +17,42 @@ func main() {
 	// parse command-line flag to determine root directory location of necessary files
 	dir := flag.String("dir", "No data directory provided", "input the directory where the initial data files are located")
 	bld := flag.String("bld", "No duild directory provided", "input the directory where the build files are located")
+	poll := flag.Int("poll", 30, "input the number of seconds between checks of the data directory for new or changed files, 0 disables reloading")
+	stale := flag.Float64("stale", 72, "input the number of hours after its epoch that a satellite's tle is flagged as stale")
+	token := flag.String("token", "", "input the bearer token clients must send to change data, changes are refused when it is empty")
+	origins := flag.String("origins", "", "input a comma separated list of other browser origins allowed to change data, such as http://localhost:3000")
 	flag.Parse()
+	models.TLEStaleAfter = time.Duration(*stale * float64(time.Hour))
+	handlers.AuthToken = *token
+	for _, origin := range strings.Split(*origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			handlers.AllowedOrigins = append(handlers.AllowedOrigins, origin)
+		}
+	}
 
 	// mount app
 	tickerChannel := time.NewTicker(time.Second).C
-	go appmount.AppMount(tickerChannel, dir)
+	var pollChannel <-chan time.Time
+	if *poll > 0 {
+		pollChannel = time.NewTicker(time.Duration(*poll) * time.Second).C
+	}
+	go appmount.AppMount(tickerChannel, dir, pollChannel)
 
 	// http router with
 	router := httprouter.New()
 	graphqlHandler := http.HandlerFunc(handlers.GraphqlHandlerFunc)
-	router.POST("/graphql", handlers.DisableCors(graphqlHandler))
+	router.POST("/graphql", handlers.AllowOrigins(graphqlHandler))
+	router.OPTIONS("/graphql", handlers.AllowOrigins(graphqlHandler))
+	router.GET("/subscriptions", handlers.SubscriptionsHandler)
+	router.POST("/upload/tle", handlers.AllowOrigins(http.HandlerFunc(handlers.UploadTLEHandlerFunc)))
+	router.OPTIONS("/upload/tle", handlers.AllowOrigins(http.HandlerFunc(handlers.UploadTLEHandlerFunc)))
+	router.GET("/export/link.csv", handlers.DisableCors(http.HandlerFunc(handlers.LinkSeriesCSVHandlerFunc)))
+	router.GET("/export/czml", handlers.DisableCors(http.HandlerFunc(handlers.CZMLHandlerFunc)))
+	router.GET("/export/kml", handlers.DisableCors(http.HandlerFunc(handlers.KMLHandlerFunc)))
+	for layer := range models.GeoJSONLayers {
+		router.GET("/api/"+layer+".geojson", handlers.DisableCors(handlers.GeoJSONHandler(layer)))
+	}
+	router.GET("/tiles/:layer/:z/:x/:y", handlers.DisableCorsHandle(handlers.TileHandler))
 	router.ServeFiles("/static/*filepath", http.Dir(*bld))
 	log.Fatal(http.ListenAndServe(":8080", router))
 }
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"math"
+)
+
+// BuildAreaGeometry turns a ring of lng/lat points traced around an area into RFC 7946 geometry. Longitudes may run
+// past ±180 or jump across it; the ring is split at the antimeridian into a MultiPolygon when it crosses it, a ring
+// circling a pole is closed along that pole, and every ring is closed and wound counterclockwise
+func BuildAreaGeometry(ring [][]float64) AreaGeometry {
+	ring = unwrapRing(ring)
+	if len(ring) > 1 && ring[0][0] == ring[len(ring)-1][0] && ring[0][1] == ring[len(ring)-1][1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return NewAreaGeometry([][][][]float64{})
+	}
+
+	// a ring that goes once around the earth encloses a pole, so it is closed along the pole to make it a plain ring
+	first, last := ring[0], ring[len(ring)-1]
+	turn := last[0] - first[0] + wrapLng(first[0]-last[0])
+	if math.Abs(turn) > 180 {
+		pole := 90.0
+		meanLat := 0.0
+		for _, p := range ring {
+			meanLat = meanLat + p[1]
+		}
+		if meanLat < 0 {
+			pole = -90.0
+		}
+		ring = append(ring, []float64{first[0] + turn, first[1]}, []float64{first[0] + turn, pole}, []float64{first[0], pole})
+	}
+
+	// shift the ring so its western edge is within -180 to 180, then anything east of 180 belongs on the other side
+	west, east := math.Inf(1), math.Inf(-1)
+	for _, p := range ring {
+		west, east = math.Min(west, p[0]), math.Max(east, p[0])
+	}
+	shift := -360 * math.Floor((west+180)/360)
+	shifted := make([][]float64, 0)
+	for _, p := range ring {
+		shifted = append(shifted, []float64{p[0] + shift, p[1]})
+	}
+	east = east + shift
+
+	pieces := [][][]float64{shifted}
+	if east > 180 {
+		pieces = [][][]float64{clipRingAtLng(shifted, 180, true), shiftRing(clipRingAtLng(shifted, 180, false), -360)}
+	}
+
+	polygons := make([][][][]float64, 0)
+	for _, piece := range pieces {
+		if len(piece) < 3 {
+			continue
+		}
+		polygons = append(polygons, [][][]float64{windCounterclockwise(closeRing(piece))})
+	}
+
+	return NewAreaGeometry(polygons)
+}
+
+// wrapLng wraps a longitude difference into -180 to 180
+func wrapLng(d float64) float64 {
+	return d - 360*math.Floor((d+180)/360)
+}
+
+// unwrapRing removes jumps of more than 180 degrees between consecutive longitudes so the ring is continuous
+func unwrapRing(ring [][]float64) [][]float64 {
+	unwrapped := make([][]float64, 0)
+	for i, p := range ring {
+		if i == 0 {
+			unwrapped = append(unwrapped, []float64{p[0], p[1]})
+			continue
+		}
+		prev := unwrapped[i-1]
+		unwrapped = append(unwrapped, []float64{prev[0] + wrapLng(p[0]-prev[0]), p[1]})
+	}
+	return unwrapped
+}
+
+// clipRingAtLng keeps the part of an open ring west (or east) of a meridian, interpolating latitude where edges cross it
+func clipRingAtLng(ring [][]float64, lng float64, keepWest bool) [][]float64 {
+	inside := func(p []float64) bool {
+		if keepWest {
+			return p[0] <= lng
+		}
+		return p[0] >= lng
+	}
+	cross := func(a []float64, b []float64) []float64 {
+		return []float64{lng, a[1] + (lng-a[0])*(b[1]-a[1])/(b[0]-a[0])}
+	}
+
+	clipped := make([][]float64, 0)
+	prev := ring[len(ring)-1]
+	for _, p := range ring {
+		if inside(p) {
+			if !inside(prev) {
+				clipped = append(clipped, cross(prev, p))
+			}
+			clipped = append(clipped, p)
+		} else if inside(prev) {
+			clipped = append(clipped, cross(prev, p))
+		}
+		prev = p
+	}
+	return clipped
+}
+
+func shiftRing(ring [][]float64, shift float64) [][]float64 {
+	shifted := make([][]float64, 0)
+	for _, p := range ring {
+		shifted = append(shifted, []float64{p[0] + shift, p[1]})
+	}
+	return shifted
+}
+
+// closeRing repeats the first position at the end of a ring unless it is already there
+func closeRing(ring [][]float64) [][]float64 {
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] == last[0] && first[1] == last[1] {
+		return ring
+	}
+	return append(ring, []float64{first[0], first[1]})
+}
+
+// windCounterclockwise reverses a closed ring whose signed lng/lat area is negative
+func windCounterclockwise(ring [][]float64) [][]float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area = area + ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	if area >= 0 {
+		return ring
+	}
+	reversed := make([][]float64, len(ring))
+	for i, p := range ring {
+		reversed[len(ring)-1-i] = p
+	}
+	return reversed
+}
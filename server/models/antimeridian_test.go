@@ -0,0 +1,170 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// lngLatArea signed lng/lat area of a closed ring, positive when it winds counterclockwise
+func lngLatArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area = area + ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+// checkAreaGeometry checks every polygon is a closed counterclockwise ring within -180 to 180 and returns their total area
+func checkAreaGeometry(t *testing.T, name string, geometry AreaGeometry) float64 {
+	total := 0.0
+	for i, polygon := range geometry.Polygons {
+		ring := polygon[0]
+		if len(ring) < 4 {
+			t.Errorf("%s: polygon %d has %d positions, want at least 4", name, i, len(ring))
+			continue
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			t.Errorf("%s: polygon %d is not closed, starts at %v and ends at %v", name, i, first, last)
+		}
+		area := lngLatArea(ring)
+		if area <= 0 {
+			t.Errorf("%s: polygon %d winds clockwise with area %g", name, i, area)
+		}
+		for _, p := range ring {
+			if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+				t.Errorf("%s: polygon %d position %v is outside -180 to 180, -90 to 90", name, i, p)
+			}
+		}
+		total = total + area
+	}
+	return total
+}
+
+// lngRange western and eastern longitude of a ring
+func lngRange(ring [][]float64) (float64, float64) {
+	west, east := math.Inf(1), math.Inf(-1)
+	for _, p := range ring {
+		west, east = math.Min(west, p[0]), math.Max(east, p[0])
+	}
+	return west, east
+}
+
+func TestBuildAreaGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		ring     [][]float64
+		polygons int
+		area     float64
+	}{
+		{"counterclockwise square", [][]float64{{10, 10}, {20, 10}, {20, 20}, {10, 20}}, 1, 100},
+		{"clockwise square is rewound", [][]float64{{10, 10}, {10, 20}, {20, 20}, {20, 10}}, 1, 100},
+		{"closed ring keeps one closing position", [][]float64{{10, 10}, {20, 10}, {20, 20}, {10, 20}, {10, 10}}, 1, 100},
+		{"square given a turn east", [][]float64{{370, 10}, {380, 10}, {380, 20}, {370, 20}}, 1, 100},
+		{"square crossing 180", [][]float64{{170, -10}, {-170, -10}, {-170, 10}, {170, 10}}, 2, 400},
+		{"square crossing 180 past 180", [][]float64{{170, -10}, {190, -10}, {190, 10}, {170, 10}}, 2, 400},
+		{"clockwise square crossing -180", [][]float64{{-170, -10}, {-170, 10}, {170, 10}, {170, -10}}, 2, 400},
+		{"two positions", [][]float64{{10, 10}, {20, 10}}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		geometry := BuildAreaGeometry(tt.ring)
+		if len(geometry.Polygons) != tt.polygons {
+			t.Errorf("%s: got %d polygons, want %d", tt.name, len(geometry.Polygons), tt.polygons)
+			continue
+		}
+		wantType := "Polygon"
+		if tt.polygons != 1 {
+			wantType = "MultiPolygon"
+		}
+		if geometry.Type != wantType {
+			t.Errorf("%s: type %s, want %s", tt.name, geometry.Type, wantType)
+		}
+		if area := checkAreaGeometry(t, tt.name, geometry); math.Abs(area-tt.area) > 1e-9 {
+			t.Errorf("%s: area %g, want %g", tt.name, area, tt.area)
+		}
+	}
+}
+
+func TestBuildAreaGeometrySplitsAtAntimeridian(t *testing.T) {
+	geometry := BuildAreaGeometry([][]float64{{170, -10}, {-170, -10}, {-170, 10}, {170, 10}})
+	if len(geometry.Polygons) != 2 {
+		t.Fatalf("got %d polygons, want 2", len(geometry.Polygons))
+	}
+
+	// one polygon lies on each side of the antimeridian and both meet it along the whole crossing
+	sides := make(map[float64]bool, 0)
+	for i, polygon := range geometry.Polygons {
+		west, east := lngRange(polygon[0])
+		switch {
+		case west == 170 && east == 180:
+			sides[180] = true
+		case west == -180 && east == -170:
+			sides[-180] = true
+		default:
+			t.Errorf("polygon %d spans %g to %g, want 170 to 180 or -180 to -170", i, west, east)
+		}
+		edge := 180.0
+		if west == -180 {
+			edge = -180
+		}
+		lats := make(map[float64]bool, 0)
+		for _, p := range polygon[0] {
+			if p[0] == edge {
+				lats[p[1]] = true
+			}
+		}
+		if !lats[-10] || !lats[10] {
+			t.Errorf("polygon %d meets %g at latitudes %v, want -10 and 10", i, edge, lats)
+		}
+	}
+	if !sides[180] || !sides[-180] {
+		t.Errorf("polygons cover sides %v, want both 180 and -180", sides)
+	}
+}
+
+func TestBuildAreaGeometryClosesAroundPole(t *testing.T) {
+	tests := []struct {
+		name string
+		lat  float64
+		pole float64
+	}{
+		{"north pole", 80, 90},
+		{"south pole", -80, -90},
+	}
+
+	for _, tt := range tests {
+		ring := make([][]float64, 0)
+		for lng := -150.0; lng <= 180; lng = lng + 30 {
+			ring = append(ring, []float64{lng, tt.lat})
+		}
+		geometry := BuildAreaGeometry(ring)
+		if len(geometry.Polygons) == 0 {
+			t.Errorf("%s: got no polygons", tt.name)
+			continue
+		}
+
+		// the ring and its closing edge along the pole enclose every longitude between the ring and the pole
+		if area := checkAreaGeometry(t, tt.name, geometry); math.Abs(area-360*10) > 1e-9 {
+			t.Errorf("%s: area %g, want %g", tt.name, area, 360.0*10)
+		}
+		west, east := 180.0, -180.0
+		for i, polygon := range geometry.Polygons {
+			w, e := lngRange(polygon[0])
+			west, east = math.Min(west, w), math.Max(east, e)
+			atPole := false
+			for _, p := range polygon[0] {
+				atPole = atPole || p[1] == tt.pole
+				if math.Abs(p[1]) < math.Abs(tt.lat) {
+					t.Errorf("%s: polygon %d position %v is on the far side of the ring from the pole", tt.name, i, p)
+				}
+			}
+			if !atPole {
+				t.Errorf("%s: polygon %d does not reach the pole", tt.name, i)
+			}
+		}
+		if west != -180 || east != 180 {
+			t.Errorf("%s: polygons span %g to %g, want -180 to 180", tt.name, west, east)
+		}
+	}
+}
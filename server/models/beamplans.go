@@ -1,7 +1,6 @@
 package models
 
 import (
-	"encoding/csv"
 	"fmt"
 	"time"
 
@@ -11,30 +10,33 @@ import (
 
 // FillBeamplanBucket fills bolt db bucket with beamplan from initial files
 func FillBeamplanBucket(f string, db *bolt.DB, t time.Time) error {
-	r := OpenCSV(f)
+	header, records, err := ReadCSVFile(f, 0)
+	if err != nil {
+		return err
+	}
+
+	err = storeBeamplanRecords(append([][]string{header}, records...), db, t.Format(time.RFC3339))
+	fmt.Println("Beamplans bucket filled")
+
+	return err
+}
+
+// storeBeamplanRecords stores csv records with a header row in the BEAMPLANS bucket under the given key
+func storeBeamplanRecords(records [][]string, db *bolt.DB, key string) error {
 	jsonMap := make([]map[string]string, 0)
-	createBeamplanCollection(r, &jsonMap)
+	createBeamplanCollection(records, &jsonMap)
 	rawJSON := helpers.FormatJSON(jsonMap)
 
-	err := db.Update(func(tx *bolt.Tx) error {
-		err := tx.Bucket([]byte("DB")).Bucket([]byte("BEAMPLANS")).Put([]byte(t.Format(time.RFC3339)), rawJSON)
+	return db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket([]byte("DB")).Bucket([]byte("BEAMPLANS")).Put([]byte(key), rawJSON)
 		if err != nil {
 			return fmt.Errorf("could not fill beamplans bucket: %v", err)
 		}
 		return nil
 	})
-	fmt.Println("Beamplans bucket filled")
-
-	return err
 }
 
-func createBeamplanCollection(r *csv.Reader, j *[]map[string]string) {
-	// write reader into array by line
-	readerList, err := r.ReadAll()
-	if err != nil {
-		panic(err)
-	}
-
+func createBeamplanCollection(readerList [][]string, j *[]map[string]string) {
 	// get csv header values from first line
 	h := readerList[0]
 
@@ -48,3 +50,16 @@ func createBeamplanCollection(r *csv.Reader, j *[]map[string]string) {
 		*j = append(*j, objectMap)
 	}
 }
+
+// readBeamplanCollection converts a stored beamplan back into csv records in header order
+func readBeamplanCollection(j []map[string]string, header []string) [][]string {
+	records := make([][]string, 0)
+	for _, objectMap := range j {
+		record := make([]string, 0)
+		for _, key := range header {
+			record = append(record, objectMap[key])
+		}
+		records = append(records, record)
+	}
+	return records
+}
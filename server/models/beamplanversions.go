@@ -0,0 +1,280 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/boltdb/bolt"
+	"github.com/graphql-go/graphql"
+)
+
+// beamplanColumns number of columns BuildMissions reads from a long format beamplan row
+const beamplanColumns = 19
+
+// beamplanIDFormat fixed width upload time used as the version id so ids sort in upload order
+const beamplanIDFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// BeamplanVersion metadata of an uploaded beamplan whose rows are stored in the BEAMPLANS bucket under the same id
+type BeamplanVersion struct {
+	ID            string   `json:"id"`
+	Satellites    []string `json:"satellites"`
+	EffectiveFrom string   `json:"effectiveFrom"`
+	UploadedAt    string   `json:"uploadedAt"`
+	ActivatedAt   string   `json:"activatedAt"`
+	Active        bool     `json:"active"`
+	Rows          int      `json:"rows"`
+	Header        []string `json:"header"`
+}
+
+// BeamplanVersionType graphql object for uploaded beamplan versions
+var BeamplanVersionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "BeamplanVersion",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.String,
+		},
+		"satellites": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"effectiveFrom": &graphql.Field{
+			Type:        graphql.String,
+			Description: "RFC3339 time the planners intend the beamplan to take effect",
+		},
+		"uploadedAt": &graphql.Field{
+			Type: graphql.String,
+		},
+		"activatedAt": &graphql.Field{
+			Type: graphql.String,
+		},
+		"active": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "missions of the satellites were last rebuilt from this beamplan",
+		},
+		"rows": &graphql.Field{
+			Type: graphql.Int,
+		},
+	},
+})
+
+// UploadBeamplan validates a long format beamplan csv for the given satellites and stores it as a new inactive version
+func UploadBeamplan(text string, satellites []string, effectiveFrom time.Time) (BeamplanVersion, error) {
+	records, err := csv.NewReader(strings.NewReader(text)).ReadAll()
+	if err != nil {
+		return BeamplanVersion{}, fmt.Errorf("could not read beamplan csv: %v", err)
+	}
+	if err := validateBeamplan(records, satellites); err != nil {
+		return BeamplanVersion{}, err
+	}
+
+	now := time.Now().UTC()
+	version := BeamplanVersion{
+		ID:            now.Format(beamplanIDFormat),
+		Satellites:    satellites,
+		EffectiveFrom: effectiveFrom.UTC().Format(time.RFC3339),
+		UploadedAt:    now.Format(time.RFC3339),
+		Rows:          len(records) - 1,
+		Header:        records[0],
+	}
+
+	if err := storeBeamplanRecords(records, DB, version.ID); err != nil {
+		return BeamplanVersion{}, err
+	}
+	err = DB.Update(func(tx *bolt.Tx) error {
+		return putBeamplanVersions(tx, version)
+	})
+	if err != nil {
+		return BeamplanVersion{}, err
+	}
+
+	return version, nil
+}
+
+// validateBeamplan checks the header, that every row belongs to one of the satellites,
+// and that every satellite is in the FLEET bucket and has at least one mission
+func validateBeamplan(records [][]string, satellites []string) error {
+	if len(records) < 2 {
+		return fmt.Errorf("beamplan must have a header row and at least one mission row")
+	}
+	header := records[0]
+	if len(header) < beamplanColumns {
+		return fmt.Errorf("beamplan must have at least %d columns, got %d", beamplanColumns, len(header))
+	}
+	seen := make(map[string]bool, 0)
+	for _, column := range header {
+		if seen[column] {
+			return fmt.Errorf("beamplan header repeats column %q", column)
+		}
+		seen[column] = true
+	}
+
+	if len(satellites) == 0 {
+		return fmt.Errorf("at least one satellite is required")
+	}
+	rows := make(map[string]int, 0)
+	for i, record := range records[1:] {
+		if record[0] == "" || record[1] == "" {
+			return fmt.Errorf("row %d is missing its satellite or mission id", i+2)
+		}
+		if !helpers.StringInSlice(record[0], satellites) {
+			return fmt.Errorf("row %d is for satellite %s which is not in satellites", i+2, record[0])
+		}
+		rows[record[0]]++
+	}
+	for _, sat := range satellites {
+		if _, ok := GetSatelliteState(sat); !ok {
+			return fmt.Errorf("satellite %s is not in the fleet", sat)
+		}
+		if rows[sat] == 0 {
+			return fmt.Errorf("beamplan has no missions for satellite %s", sat)
+		}
+	}
+
+	return nil
+}
+
+// ActivateBeamplan rebuilds the missions in the FLEET bucket of every satellite in the beamplan version. Every satellite
+// is checked before anything is written, and the missions and version metadata are written in one transaction.
+// A version cannot be activated before its effectiveFrom time
+func ActivateBeamplan(id string) (BeamplanVersion, error) {
+	versions := GetBeamplanVersions()
+	var version BeamplanVersion
+	found := false
+	for _, v := range versions {
+		if v.ID == id {
+			version = v
+			found = true
+		}
+	}
+	if !found {
+		return BeamplanVersion{}, fmt.Errorf("beamplan %s not found", id)
+	}
+
+	now := time.Now().UTC()
+	effectiveFrom, err := time.Parse(time.RFC3339, version.EffectiveFrom)
+	if err != nil {
+		return BeamplanVersion{}, fmt.Errorf("beamplan %s has an invalid effectiveFrom %q: %v", id, version.EffectiveFrom, err)
+	}
+	if now.Before(effectiveFrom) {
+		return BeamplanVersion{}, fmt.Errorf("beamplan %s is not effective until %s", id, version.EffectiveFrom)
+	}
+
+	var jsonMap []map[string]string
+	if err := json.Unmarshal(GetDBObject(id, DB, "DB", "BEAMPLANS"), &jsonMap); err != nil {
+		return BeamplanVersion{}, fmt.Errorf("could not read beamplan %s: %v", id, err)
+	}
+	records := readBeamplanCollection(jsonMap, version.Header)
+
+	states := make(map[string]SatelliteState, 0)
+	for _, sat := range version.Satellites {
+		satstate, ok := GetSatelliteState(sat)
+		if !ok {
+			return BeamplanVersion{}, fmt.Errorf("satellite %s is not in the fleet", sat)
+		}
+		satstate.Missions = BuildMissions(records, sat)
+		if len(satstate.Missions) == 0 {
+			return BeamplanVersion{}, fmt.Errorf("beamplan %s has no missions for satellite %s", id, sat)
+		}
+		states[sat] = satstate
+	}
+
+	// the activated version supersedes any other version flying the same satellites
+	version.Active = true
+	version.ActivatedAt = now.Format(time.RFC3339)
+	updated := []BeamplanVersion{version}
+	for _, v := range versions {
+		if v.ID == id || !v.Active {
+			continue
+		}
+		for _, sat := range v.Satellites {
+			if helpers.StringInSlice(sat, version.Satellites) {
+				v.Active = false
+				updated = append(updated, v)
+				break
+			}
+		}
+	}
+
+	err = DB.Update(func(tx *bolt.Tx) error {
+		for sat, satstate := range states {
+			if err := putSatelliteState(tx, sat, satstate); err != nil {
+				return err
+			}
+		}
+		return putBeamplanVersions(tx, updated...)
+	})
+	if err != nil {
+		return BeamplanVersion{}, err
+	}
+
+	return version, nil
+}
+
+// activeBeamplanMissions missions of a satellite in the beamplan version last activated for it, false when the
+// satellite flies the beamplan file the roster assigns it
+func activeBeamplanMissions(tx *bolt.Tx, sat string) ([]BeamplanMission, bool) {
+	var active BeamplanVersion
+	found := false
+	tx.Bucket([]byte("DB")).Bucket([]byte("BEAMPLANVERSIONS")).ForEach(func(k, v []byte) error {
+		var version BeamplanVersion
+		json.Unmarshal(v, &version)
+		if version.Active && helpers.StringInSlice(sat, version.Satellites) && version.ActivatedAt >= active.ActivatedAt {
+			active = version
+			found = true
+		}
+		return nil
+	})
+	if !found {
+		return nil, false
+	}
+
+	var jsonMap []map[string]string
+	if err := json.Unmarshal(tx.Bucket([]byte("DB")).Bucket([]byte("BEAMPLANS")).Get([]byte(active.ID)), &jsonMap); err != nil {
+		fmt.Println("Could not read beamplan", active.ID, "of satellite", sat, ":", err)
+		return nil, false
+	}
+
+	return BuildMissions(readBeamplanCollection(jsonMap, active.Header), sat), true
+}
+
+// putBeamplanVersions writes beamplan version metadata to the BEAMPLANVERSIONS bucket
+func putBeamplanVersions(tx *bolt.Tx, versions ...BeamplanVersion) error {
+	b := tx.Bucket([]byte("DB")).Bucket([]byte("BEAMPLANVERSIONS"))
+	for _, v := range versions {
+		versionBytes, err := json.MarshalIndent(v, "", "\t")
+		if err != nil {
+			return err
+		}
+		versionBytes = bytes.Replace(versionBytes, []byte("\\u0026"), []byte("&"), -1)
+		if err := b.Put([]byte(v.ID), versionBytes); err != nil {
+			return fmt.Errorf("could not fill beamplan versions bucket: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetBeamplanVersions returns every uploaded beamplan version, oldest first
+func GetBeamplanVersions() []BeamplanVersion {
+	versions := make([]BeamplanVersion, 0)
+	err := DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("BEAMPLANVERSIONS"))
+		b.ForEach(func(k, v []byte) error {
+			var version BeamplanVersion
+			json.Unmarshal(v, &version)
+			versions = append(versions, version)
+			return nil
+		})
+		return nil
+	})
+	helpers.PanicErrors(err)
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID < versions[j].ID
+	})
+
+	return versions
+}
@@ -3,90 +3,124 @@ package models
 import (
 	"encoding/csv"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
-
-	satellite "github.com/joshuaferrara/go-satellite"
+	"time"
 )
 
-// GetHeader from csv files
-func GetHeader(cr *csv.Reader) []string {
-	record, err := cr.Read()
-	if err == io.EOF {
-		return nil
-	}
+// ReadCSVFile reads a csv file into its header and the rows after it, leaving out rows that repeat the header.
+// fieldsPerRecord is handed to the csv reader, -1 allowing rows of different lengths
+func ReadCSVFile(f string, fieldsPerRecord int) ([]string, [][]string, error) {
+	file, err := os.Open(f)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
+	defer file.Close()
 
-	return record
-}
-
-// OpenCSV opens a csv file
-func OpenCSV(f string) *csv.Reader {
-	file, err := os.Open(f)
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = fieldsPerRecord
+	records, err := r.ReadAll()
 	if err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+		return nil, nil, fmt.Errorf("could not read %s: %v", filepath.Base(f), err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s has no header row", filepath.Base(f))
 	}
 
-	r := csv.NewReader(file)
+	header := records[0]
+	rows := make([][]string, 0)
+	for _, record := range records[1:] {
+		if record[0] == header[0] {
+			continue
+		}
+		rows = append(rows, record)
+	}
 
-	return r
+	return header, rows, nil
 }
 
-// GetBeamplanFiles organizes beamplan files into map sorted by active, spare, M001, M013
-func GetBeamplanFiles(files []string, fileregex *regexp.Regexp, regexmap map[string]*regexp.Regexp, bpfiles map[string]string) {
+// matchingFiles returns the files whose names match the regular expression
+func matchingFiles(files []string, fileregex *regexp.Regexp) []string {
+	matched := make([]string, 0)
+	for _, file := range files {
+		if fileregex != nil && fileregex.MatchString(filepath.Base(file)) {
+			matched = append(matched, file)
+		}
+	}
+	return matched
+}
+
+// GetBeamplanFiles organizes beamplan files into map keyed by file name for lookup from the fleet roster
+func GetBeamplanFiles(files []string, fileregex *regexp.Regexp, bpfiles map[string]string) {
 	for _, file := range files {
 		switch true {
 		case fileregex.MatchString(filepath.Base(file)):
-			switch true {
-			case regexmap["mute"].MatchString(filepath.Base(file)):
-				bpfiles["SPARE"] = file
-			case regexmap["B3"].MatchString(filepath.Base(file)):
-				bpfiles["B3"] = file
-			case regexmap["M001"].MatchString(filepath.Base(file)):
-				bpfiles["M001"] = file
-			case regexmap["M013"].MatchString(filepath.Base(file)):
-				bpfiles["M013"] = file
-			default:
-				bpfiles["ACTIVE"] = file
-			}
+			bpfiles[filepath.Base(file)] = file
 		}
 	}
 }
 
-// ProcessInitFiles checks file names against list of regular expressions and calls handlers based on results
+// ProcessInitFiles fills the TARGETS, ZONES, CATSEYES and ROSTER buckets from the matching files. A bucket whose files
+// cannot be read keeps its previous contents and the error is logged
 func ProcessInitFiles(files []string, regexmap map[string]*regexp.Regexp) {
-	for _, file := range files {
-		switch true {
-		case regexmap["TARGETS"].MatchString(filepath.Base(file)):
-			FillTargetsBucket(file)
-		case regexmap["ZONES"].MatchString(filepath.Base(file)):
-			FillZonesBucket(file)
-			FillCatseyesBucket()
-		default:
-			continue
+	if targets := matchingFiles(files, regexmap["TARGETS"]); len(targets) > 0 {
+		if err := FillTargetsBucket(targets); err != nil {
+			fmt.Println("Could not load targets:", err)
+		}
+	}
+	if zones := matchingFiles(files, regexmap["ZONES"]); len(zones) > 0 {
+		if err := FillZonesBucket(zones); err != nil {
+			fmt.Println("Could not load zones:", err)
+		}
+	}
+	if rosters := matchingFiles(files, regexmap["ROSTER"]); len(rosters) > 0 {
+		if err := FillRosterBucket(rosters); err != nil {
+			fmt.Println("Could not load roster:", err)
 		}
 	}
 }
 
-// ProcessEphemeris takes tle and beamplan and initializes db entries
-func ProcessEphemeris(files []string, regexmap map[string]*regexp.Regexp, bpfilelist map[string]string) map[string]satellite.Satellite {
-	sgp4sats := make(map[string]satellite.Satellite, 0)
+// ProcessEphemeris reads every tle and OMM file, builds the satellite states from the roster and beamplan files and
+// replaces the FLEET bucket with them in one transaction. Nothing is changed when a file cannot be read
+func ProcessEphemeris(files []string, regexmap map[string]*regexp.Regexp, bpfilelist map[string]string) (map[string]Orbit, error) {
+	// ephemerides are loaded first so satellites whose roster source is oem pick them up
+	LoadOEMFiles(files, regexmap["OEM"])
+
+	tlemap := make(map[string]map[string]string, 0)
+	found := false
 	for _, file := range files {
+		var elements map[string]map[string]string
+		var err error
 		switch true {
+		case regexmap["OEM"] != nil && regexmap["OEM"].MatchString(filepath.Base(file)):
+			continue
+		case regexmap["OMM"] != nil && regexmap["OMM"].MatchString(filepath.Base(file)):
+			elements, err = GetOMMTLES(file)
 		case regexmap["ephemeris"].MatchString(filepath.Base(file)):
-			tlemap := GetTLES(file)
-			GetBeamplan(tlemap, bpfilelist)
-			satStates := GetSatelliteStates()
-			sgp4sats = InitSatelliteSGP4(satStates)
+			elements, err = GetTLES(file)
 		default:
 			continue
 		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for sat, tle := range elements {
+			tlemap[sat] = tle
+		}
+	}
+	if !found {
+		return make(map[string]Orbit, 0), nil
+	}
+
+	satStates, err := GetBeamplan(tlemap, bpfilelist)
+	if err != nil {
+		return nil, err
 	}
-	return sgp4sats
+	if err := FillFleetBucket(satStates); err != nil {
+		return nil, err
+	}
+
+	return InitSatelliteOrbits(GetSatelliteStates(), time.Now()), nil
 }
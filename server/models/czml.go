@@ -0,0 +1,338 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// czmlInterpolationDegree lagrange degree Cesium uses between sampled satellite positions
+const czmlInterpolationDegree = 5
+
+// CZMLPacket single packet of a CZML document, fields left empty are omitted
+type CZMLPacket struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name,omitempty"`
+	Version      string                 `json:"version,omitempty"`
+	Clock        *CZMLClock             `json:"clock,omitempty"`
+	Availability string                 `json:"availability,omitempty"`
+	Position     *CZMLPosition          `json:"position,omitempty"`
+	Point        *CZMLPoint             `json:"point,omitempty"`
+	Label        *CZMLLabel             `json:"label,omitempty"`
+	Path         *CZMLPath              `json:"path,omitempty"`
+	Polygon      *CZMLPolygon           `json:"polygon,omitempty"`
+	Properties   map[string]interface{} `json:"properties,omitempty"`
+}
+
+// CZMLClock clock of the document packet
+type CZMLClock struct {
+	Interval    string  `json:"interval"`
+	CurrentTime string  `json:"currentTime"`
+	Multiplier  float64 `json:"multiplier"`
+	Range       string  `json:"range"`
+	Step        string  `json:"step"`
+}
+
+// CZMLPosition static or sampled position in degrees and meters above the WGS84 ellipsoid.
+// Sampled positions are flattened [seconds since epoch, lng, lat, height] quadruples
+type CZMLPosition struct {
+	Epoch                  string    `json:"epoch,omitempty"`
+	InterpolationAlgorithm string    `json:"interpolationAlgorithm,omitempty"`
+	InterpolationDegree    int       `json:"interpolationDegree,omitempty"`
+	CartographicDegrees    []float64 `json:"cartographicDegrees"`
+}
+
+// CZMLColor rgba color with 0 - 255 components
+type CZMLColor struct {
+	RGBA []int `json:"rgba"`
+}
+
+// CZMLPoint point graphics
+type CZMLPoint struct {
+	PixelSize float64   `json:"pixelSize"`
+	Color     CZMLColor `json:"color"`
+}
+
+// CZMLLabel label graphics
+type CZMLLabel struct {
+	Text        string    `json:"text"`
+	Font        string    `json:"font"`
+	FillColor   CZMLColor `json:"fillColor"`
+	PixelOffset struct {
+		Cartesian2 []float64 `json:"cartesian2"`
+	} `json:"pixelOffset"`
+}
+
+// CZMLPath trail drawn behind and ahead of a sampled position, in seconds
+type CZMLPath struct {
+	LeadTime  float64   `json:"leadTime"`
+	TrailTime float64   `json:"trailTime"`
+	Width     float64   `json:"width"`
+	Material  CZMLSolid `json:"material"`
+}
+
+// CZMLSolid solid color material
+type CZMLSolid struct {
+	SolidColor struct {
+		Color CZMLColor `json:"color"`
+	} `json:"solidColor"`
+}
+
+// CZMLPolygon polygon graphics whose show property changes over intervals
+type CZMLPolygon struct {
+	Positions    CZMLPosition       `json:"positions"`
+	Material     CZMLSolid          `json:"material"`
+	Outline      bool               `json:"outline"`
+	OutlineColor CZMLColor          `json:"outlineColor"`
+	Show         []CZMLIntervalBool `json:"show"`
+}
+
+// CZMLIntervalBool boolean value over a time interval
+type CZMLIntervalBool struct {
+	Interval string `json:"interval"`
+	Boolean  bool   `json:"boolean"`
+}
+
+// CZMLIntervalString string value over a time interval
+type CZMLIntervalString struct {
+	Interval string `json:"interval"`
+	String   string `json:"string"`
+}
+
+// czml colors of each kind of entity
+var (
+	czmlSatelliteColor = CZMLColor{[]int{255, 214, 0, 255}}
+	czmlTargetColor    = CZMLColor{[]int{0, 200, 255, 255}}
+	czmlGatewayColor   = CZMLColor{[]int{255, 90, 90, 255}}
+	czmlCatseyeFill    = CZMLColor{[]int{0, 255, 128, 64}}
+	czmlCatseyeOutline = CZMLColor{[]int{0, 255, 128, 255}}
+	czmlLabelColor     = CZMLColor{[]int{255, 255, 255, 255}}
+)
+
+// czmlInterval formats a CZML iso 8601 time interval
+func czmlInterval(start time.Time, end time.Time) string {
+	return start.UTC().Format(time.RFC3339) + "/" + end.UTC().Format(time.RFC3339)
+}
+
+// BuildCZML builds a CZML document of the FLEET satellites sampled from start to end every step, the TARGETS targets and
+// the CATSEYES polygons. Each satellite carries the missions it flies over time and each catseye is shown only while a
+// satellite is flying that zone's mission
+func BuildCZML(start time.Time, end time.Time, step time.Duration) ([]CZMLPacket, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	if int(end.Sub(start)/step)+1 > MaxGroundTrackPoints {
+		return nil, fmt.Errorf("window would produce more than %d samples per satellite, use a larger step", MaxGroundTrackPoints)
+	}
+	times := make([]time.Time, 0)
+	for t := start; !t.After(end); t = t.Add(step) {
+		times = append(times, t)
+	}
+	window := czmlInterval(start, end)
+
+	packets := []CZMLPacket{{
+		ID:      "document",
+		Name:    "worldmap",
+		Version: "1.0",
+		Clock: &CZMLClock{
+			Interval:    window,
+			CurrentTime: start.UTC().Format(time.RFC3339),
+			Multiplier:  60,
+			Range:       "LOOP_STOP",
+			Step:        "SYSTEM_CLOCK_MULTIPLIER",
+		},
+	}}
+
+	zones := GetZones()
+	satStates := GetSatelliteStates()
+	ids := make([]string, 0)
+	for id := range satStates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// served[zone id][sample] is true when any satellite flies the zone's mission at that sample
+	served := make(map[string][]bool, 0)
+	for _, id := range ids {
+		sat, ok := SatelliteOrbitOver(id, start, end)
+		if !ok {
+			continue
+		}
+		points, err := PropagateTrack(sat, start, end, step)
+		if err != nil {
+			return nil, err
+		}
+
+		samples := make([]float64, 0)
+		missions := make([]string, 0)
+		for i, p := range points {
+			samples = append(samples, p.Time.Sub(start).Seconds(), p.Longitude, p.Latitude, p.Altitude*1000)
+
+			flying := make([]string, 0)
+			for _, zone := range zones {
+				if !ZoneContainsLng(zone, p.Longitude) {
+					continue
+				}
+				for _, m := range satStates[id].Missions {
+					if m.ID != zone.Properties.ZoneID {
+						continue
+					}
+					flying = append(flying, m.ID)
+					if served[m.ID] == nil {
+						served[m.ID] = make([]bool, len(times))
+					}
+					served[m.ID][i] = true
+				}
+			}
+			sort.Strings(flying)
+			missions = append(missions, strings.Join(flying, ","))
+		}
+
+		packets = append(packets, CZMLPacket{
+			ID:           "satellite/" + id,
+			Name:         id,
+			Availability: window,
+			Position: &CZMLPosition{
+				Epoch:                  start.UTC().Format(time.RFC3339),
+				InterpolationAlgorithm: "LAGRANGE",
+				InterpolationDegree:    czmlInterpolationDegree,
+				CartographicDegrees:    samples,
+			},
+			Point: &CZMLPoint{PixelSize: 8, Color: czmlSatelliteColor},
+			Label: czmlLabel(id),
+			Path:  czmlPath(),
+			Properties: map[string]interface{}{
+				"mission": czmlStringIntervals(times, missions, end),
+			},
+		})
+	}
+
+	for _, target := range GetTargets() {
+		color := czmlTargetColor
+		if TargetFlagSet(target.Properties.GatewayFlag) {
+			color = czmlGatewayColor
+		}
+		coordinates := target.Geometry.Coordinates
+		if len(coordinates) < 2 {
+			continue
+		}
+		packets = append(packets, CZMLPacket{
+			ID:   "target/" + target.Properties.TargetID,
+			Name: target.Properties.LongName,
+			Position: &CZMLPosition{
+				CartographicDegrees: []float64{coordinates[0], coordinates[1], convertStringToFloat64(target.Properties.Altitude)},
+			},
+			Point: &CZMLPoint{PixelSize: 6, Color: color},
+			Label: czmlLabel(target.Properties.ShortName),
+			Properties: map[string]interface{}{
+				"gatewayFlag": target.Properties.GatewayFlag,
+				"ttcFlag":     target.Properties.TTCFlag,
+			},
+		})
+	}
+
+	for _, catseye := range GetCatseyes() {
+		show, ok := served[catseye.Properties.ZoneID]
+		if !ok {
+			show = make([]bool, len(times))
+		}
+
+		// a catseye split at the antimeridian becomes one packet per polygon, the first keeping the zone's id
+		for i, polygon := range catseye.Geometry.Polygons {
+			if len(polygon) == 0 {
+				continue
+			}
+			positions := make([]float64, 0)
+			for _, c := range polygon[0] {
+				positions = append(positions, c[0], c[1], 0)
+			}
+			id := "catseye/" + catseye.Properties.ZoneID
+			if i > 0 {
+				id = fmt.Sprintf("%s#%d", id, i+1)
+			}
+
+			packets = append(packets, CZMLPacket{
+				ID:   id,
+				Name: catseye.Properties.ZoneID,
+				Polygon: &CZMLPolygon{
+					Positions:    CZMLPosition{CartographicDegrees: positions},
+					Material:     czmlSolid(czmlCatseyeFill),
+					Outline:      true,
+					OutlineColor: czmlCatseyeOutline,
+					Show:         czmlBoolIntervals(times, show, end),
+				},
+				Properties: map[string]interface{}{
+					"subregion": catseye.Properties.Subregion,
+					"gateway":   catseye.Properties.Gateway,
+				},
+			})
+		}
+	}
+
+	return packets, nil
+}
+
+func czmlLabel(text string) *CZMLLabel {
+	label := &CZMLLabel{
+		Text:      text,
+		Font:      "11pt sans-serif",
+		FillColor: czmlLabelColor,
+	}
+	label.PixelOffset.Cartesian2 = []float64{0, -16}
+	return label
+}
+
+func czmlSolid(color CZMLColor) CZMLSolid {
+	var material CZMLSolid
+	material.SolidColor.Color = color
+	return material
+}
+
+func czmlPath() *CZMLPath {
+	return &CZMLPath{
+		LeadTime:  0,
+		TrailTime: 3600,
+		Width:     1,
+		Material:  czmlSolid(czmlSatelliteColor),
+	}
+}
+
+// czmlStringIntervals merges consecutive samples with the same value into intervals, each lasting until the next change
+func czmlStringIntervals(times []time.Time, values []string, end time.Time) []CZMLIntervalString {
+	intervals := make([]CZMLIntervalString, 0)
+	for i := 0; i < len(times); {
+		j := i
+		for j < len(times) && values[j] == values[i] {
+			j++
+		}
+		stop := end
+		if j < len(times) {
+			stop = times[j]
+		}
+		intervals = append(intervals, CZMLIntervalString{czmlInterval(times[i], stop), values[i]})
+		i = j
+	}
+	return intervals
+}
+
+// czmlBoolIntervals merges consecutive samples with the same value into intervals, each lasting until the next change
+func czmlBoolIntervals(times []time.Time, values []bool, end time.Time) []CZMLIntervalBool {
+	intervals := make([]CZMLIntervalBool, 0)
+	for i := 0; i < len(times); {
+		j := i
+		for j < len(times) && values[j] == values[i] {
+			j++
+		}
+		stop := end
+		if j < len(times) {
+			stop = times[j]
+		}
+		intervals = append(intervals, CZMLIntervalBool{czmlInterval(times[i], stop), values[i]})
+		i = j
+	}
+	return intervals
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/alexmspina/worldmap/server/helpers"
 	"github.com/boltdb/bolt"
@@ -45,7 +46,23 @@ func SetupDB() (*bolt.DB, error) {
 		if err != nil {
 			return fmt.Errorf("could not create catseyes bucket: %v", err)
 		}
-		return nil
+		_, err = root.CreateBucketIfNotExists([]byte("ROSTER"))
+		if err != nil {
+			return fmt.Errorf("could not create roster bucket: %v", err)
+		}
+		_, err = root.CreateBucketIfNotExists([]byte("BEAMPLANVERSIONS"))
+		if err != nil {
+			return fmt.Errorf("could not create beamplan versions bucket: %v", err)
+		}
+		_, err = root.CreateBucketIfNotExists([]byte("TLEHISTORY"))
+		if err != nil {
+			return fmt.Errorf("could not create tle history bucket: %v", err)
+		}
+		_, err = root.CreateBucketIfNotExists([]byte("SCHEMA"))
+		if err != nil {
+			return fmt.Errorf("could not create schema bucket: %v", err)
+		}
+		return migrateBuckets(tx)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("could not setup buckets, %v", err)
@@ -54,6 +71,51 @@ func SetupDB() (*bolt.DB, error) {
 	return db, nil
 }
 
+// bucketVersions current format version of each versioned bucket. The SCHEMA bucket records the version a database
+// holds, a bucket missing from it is at version 1
+var bucketVersions = map[string]int{
+	"CATSEYES": 2,
+}
+
+// bucketMigrations upgrades a bucket from the version it is keyed by to the next one
+var bucketMigrations = map[string]map[int]func(tx *bolt.Tx) error{
+	"CATSEYES": {
+		1: migrateCatseyesGeoJSON,
+	},
+}
+
+// migrateBuckets brings every versioned bucket up to its current version, one migration at a time
+func migrateBuckets(tx *bolt.Tx) error {
+	schema := tx.Bucket([]byte("DB")).Bucket([]byte("SCHEMA"))
+	for bucket, current := range bucketVersions {
+		version := 1
+		if v := schema.Get([]byte(bucket)); v != nil {
+			parsed, err := strconv.Atoi(string(v))
+			if err != nil {
+				return fmt.Errorf("could not read %s schema version: %v", bucket, err)
+			}
+			version = parsed
+		}
+
+		for ; version < current; version++ {
+			migrate, ok := bucketMigrations[bucket][version]
+			if !ok {
+				return fmt.Errorf("no migration of %s bucket from version %d", bucket, version)
+			}
+			if err := migrate(tx); err != nil {
+				return fmt.Errorf("could not migrate %s bucket to version %d: %v", bucket, version+1, err)
+			}
+			fmt.Printf("%s bucket migrated to version %d\n", bucket, version+1)
+		}
+
+		err := schema.Put([]byte(bucket), []byte(strconv.Itoa(version)))
+		if err != nil {
+			return fmt.Errorf("could not record %s schema version: %v", bucket, err)
+		}
+	}
+	return nil
+}
+
 // GetDbBucket pulls the desired bucket from the given database
 func GetDbBucket(db *bolt.DB, mb string, b string, l *[][][]byte) {
 	err := db.View(func(tx *bolt.Tx) error {
@@ -78,3 +140,21 @@ func GetDBObject(thing string, db *bolt.DB, dbBucket string, subBucket string) [
 
 	return b
 }
+
+// replaceBucket empties a sub bucket of the root DB bucket and fills it with features keyed by id. It runs inside the
+// caller's transaction, so readers see either the old or the new contents and a failure leaves the old ones in place
+func replaceBucket(tx *bolt.Tx, b string, features map[string]interface{}) error {
+	root := tx.Bucket([]byte("DB"))
+	if err := root.DeleteBucket([]byte(b)); err != nil && err != bolt.ErrBucketNotFound {
+		return fmt.Errorf("could not clear %s bucket: %v", b, err)
+	}
+	if _, err := root.CreateBucket([]byte(b)); err != nil {
+		return fmt.Errorf("could not create %s bucket: %v", b, err)
+	}
+	for id, feature := range features {
+		if err := putFeature(tx, b, id, feature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/graphql-go/graphql"
+)
+
+// speedOfLight in kilometers per second
+const speedOfLight = 299792.458
+
+// LinkSample link geometry between a target and a satellite at a single instant
+type LinkSample struct {
+	Time              string  `json:"time"`
+	Azimuth           float64 `json:"azimuth"`
+	Elevation         float64 `json:"elevation"`
+	Range             float64 `json:"range"`
+	RangeRate         float64 `json:"rangeRate"`
+	DopplerShift      float64 `json:"dopplerShift"`
+	FreeSpacePathLoss float64 `json:"freeSpacePathLoss"`
+	Delay             float64 `json:"delay"`
+}
+
+// LinkSampleType graphql object for link geometry samples
+var LinkSampleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LinkSample",
+	Fields: graphql.Fields{
+		"time": &graphql.Field{
+			Type: graphql.String,
+		},
+		"azimuth": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"elevation": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"range": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "slant range in kilometers",
+		},
+		"rangeRate": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "slant range rate in kilometers per second, positive when receding",
+		},
+		"dopplerShift": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "doppler offset of the carrier in hertz",
+		},
+		"freeSpacePathLoss": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "free-space path loss of the carrier in decibels",
+		},
+		"delay": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "one-way propagation delay in milliseconds",
+		},
+	},
+})
+
+// BuildLinkSample converts look angles into doppler and link geometry for a carrier frequency in hertz
+func BuildLinkSample(look LookAngles, frequency float64) LinkSample {
+	return LinkSample{
+		Time:              look.Time,
+		Azimuth:           look.Azimuth,
+		Elevation:         look.Elevation,
+		Range:             look.Range,
+		RangeRate:         look.RangeRate,
+		DopplerShift:      -frequency * look.RangeRate / speedOfLight,
+		FreeSpacePathLoss: 20 * math.Log10(4*math.Pi*look.Range*1000*frequency/(speedOfLight*1000)),
+		Delay:             look.Range / speedOfLight * 1000,
+	}
+}
+
+// BuildLinkSeries samples the link between a TARGETS target and a FLEET satellite from start to end every step
+func BuildLinkSeries(targetID string, satID string, start time.Time, end time.Time, step time.Duration, frequency float64) ([]LinkSample, error) {
+	if frequency <= 0 {
+		return nil, fmt.Errorf("frequency must be positive")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+	if int(end.Sub(start)/step)+1 > MaxGroundTrackPoints {
+		return nil, fmt.Errorf("window would produce more than %d samples, use a larger step", MaxGroundTrackPoints)
+	}
+	target, ok := GetTargetFeature(targetID)
+	if !ok {
+		return nil, fmt.Errorf("target %s not found", targetID)
+	}
+	sat, ok := SatelliteOrbitOver(satID, start, end)
+	if !ok {
+		return nil, fmt.Errorf("satellite %s not found", satID)
+	}
+	o := TargetObserver(target)
+
+	samples := make([]LinkSample, 0)
+	for t := start; !t.After(end); t = t.Add(step) {
+		samples = append(samples, BuildLinkSample(LookAnglesAt(o, sat, t), frequency))
+	}
+
+	return samples, nil
+}
+
+// WriteLinkSeriesCSV writes link samples as csv with a header row
+func WriteLinkSeriesCSV(w io.Writer, samples []LinkSample) error {
+	cw := csv.NewWriter(w)
+	header := []string{"time", "azimuth_deg", "elevation_deg", "range_km", "range_rate_km_s", "doppler_hz", "fspl_db", "delay_ms"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		record := []string{
+			s.Time,
+			helpers.ConvertFloat64ToString(s.Azimuth),
+			helpers.ConvertFloat64ToString(s.Elevation),
+			helpers.ConvertFloat64ToString(s.Range),
+			helpers.ConvertFloat64ToString(s.RangeRate),
+			helpers.ConvertFloat64ToString(s.DopplerShift),
+			helpers.ConvertFloat64ToString(s.FreeSpacePathLoss),
+			helpers.ConvertFloat64ToString(s.Delay),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBuildLinkSample(t *testing.T) {
+	// free-space path loss is 20 log10(d km) + 20 log10(f MHz) + 32.45 dB, and light covers 299.792458 km a millisecond
+	tests := []struct {
+		name      string
+		rng       float64
+		rangeRate float64
+		frequency float64
+		doppler   float64
+		fspl      float64
+		delay     float64
+	}{
+		{"s-band approaching at 7 km/s", 1000, -7, 2.2e9, 51368.870660515, 159.296236838, 3.335640952},
+		{"s-band receding at 7 km/s", 1000, 7, 2.2e9, -51368.870660515, 159.296236838, 3.335640952},
+		{"uhf approaching at 7.5 km/s", 1000, -7.5, 437e6, 10932.563220119, 145.257411961, 3.335640952},
+		{"overhead without range rate", 500, 0, 2.2e9, 0, 153.275636925, 1.667820476},
+		{"one light millisecond at 1 GHz", 299.792458, 0, 1e9, 0, 141.984197280, 1},
+		{"1 km at 1 GHz", 1, 0, 1e9, 0, 92.447783222, 0.003335641},
+		{"geostationary ku-band", 35786, 0, 12e9, 0, 205.105671295, 119.369247108},
+	}
+
+	for _, tt := range tests {
+		got := BuildLinkSample(LookAngles{Range: tt.rng, RangeRate: tt.rangeRate}, tt.frequency)
+		if math.Abs(got.DopplerShift-tt.doppler) > 1e-6 {
+			t.Errorf("%s: doppler shift %g Hz, want %g", tt.name, got.DopplerShift, tt.doppler)
+		}
+		if math.Abs(got.FreeSpacePathLoss-tt.fspl) > 1e-3 {
+			t.Errorf("%s: free-space path loss %g dB, want %g", tt.name, got.FreeSpacePathLoss, tt.fspl)
+		}
+		if math.Abs(got.Delay-tt.delay) > 1e-6 {
+			t.Errorf("%s: delay %g ms, want %g", tt.name, got.Delay, tt.delay)
+		}
+	}
+}
+
+func TestBuildLinkSampleDopplerSign(t *testing.T) {
+	// an approaching satellite is heard above the carrier, a receding one below
+	for _, rangeRate := range []float64{-7.5, -0.1, 0.1, 7.5} {
+		got := BuildLinkSample(LookAngles{Range: 1000, RangeRate: rangeRate}, 2.2e9)
+		if approaching := rangeRate < 0; approaching != (got.DopplerShift > 0) {
+			t.Errorf("range rate %g km/s: doppler shift %g Hz has the wrong sign", rangeRate, got.DopplerShift)
+		}
+	}
+}
+
+func TestWriteLinkSeriesCSV(t *testing.T) {
+	var b bytes.Buffer
+	samples := []LinkSample{BuildLinkSample(LookAngles{Time: "2020-01-01T00:00:00Z", Azimuth: 90, Elevation: 45, Range: 1000, RangeRate: -7}, 2.2e9)}
+	if err := WriteLinkSeriesCSV(&b, samples); err != nil {
+		t.Fatalf("WriteLinkSeriesCSV: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want a header and one sample", len(lines))
+	}
+	if want := "time,azimuth_deg,elevation_deg,range_km,range_rate_km_s,doppler_hz,fspl_db,delay_ms"; lines[0] != want {
+		t.Errorf("header %q, want %q", lines[0], want)
+	}
+	if fields := strings.Split(lines[1], ","); len(fields) != 8 || fields[0] != "2020-01-01T00:00:00Z" || fields[3] != "1000.000000" || fields[4] != "-7.000000" {
+		t.Errorf("sample row %q, want 8 columns with range 1000.000000 and range rate -7.000000", lines[1])
+	}
+}
@@ -4,10 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"os"
-	"strings"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/alexmspina/worldmap/server/helpers"
@@ -135,6 +134,7 @@ type satelliteProperties struct {
 	Velocity float64           `json:"velocity"`
 	Altitude float64           `json:"altitude"`
 	Mission  []BeamplanMission `json:"mission"`
+	Source   string            `json:"source"`
 }
 
 // SatellitePropsType graphql type for target feature properties
@@ -150,6 +150,10 @@ var SatellitePropsType = graphql.NewObject(graphql.ObjectConfig{
 		"altitude": &graphql.Field{
 			Type: graphql.String,
 		},
+		"source": &graphql.Field{
+			Type:        graphql.String,
+			Description: "orbit source the position was propagated with, sgp4 or oem",
+		},
 		"mission": &graphql.Field{
 			Type:        graphql.NewList(MissionType),
 			Description: "Get the beams from the current mission",
@@ -163,11 +167,21 @@ var SatellitePropsType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
-// SatelliteFeature geoJSON structure for satellites in motion
+// SatelliteFeature geoJSON structure for satellites in motion. at is the time the position was propagated to,
+// and is not kept in the SATPOS bucket
 type SatelliteFeature struct {
 	Type       string              `json:"type"`
 	Geometry   PointGeometry       `json:"geometry"`
 	Properties satelliteProperties `json:"properties"`
+	at         time.Time
+}
+
+// propagatedAt time a satellite feature's position is for, now for the live positions read from SATPOS
+func (s SatelliteFeature) propagatedAt() time.Time {
+	if s.at.IsZero() {
+		return time.Now().UTC()
+	}
+	return s.at
 }
 
 // SatelliteType graphql object for satellite features
@@ -197,6 +211,53 @@ var SatelliteType = graphql.NewObject(graphql.ObjectConfig{
 				return s.Properties, nil
 			},
 		},
+		"footprint": &graphql.Field{
+			Type:        FootprintType,
+			Description: "ground visible from the satellite above an elevation mask",
+			Args:        footprintArgs(),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(SatelliteFeature)
+
+				return resolveFootprint(s.Properties.ID, s, params.Args)
+			},
+		},
+		"tleEpoch": &graphql.Field{
+			Type:        graphql.String,
+			Description: "RFC3339 epoch of the tle the satellite's position was propagated with",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				age, err := satelliteTLEAge(params)
+				if err != nil {
+					return nil, err
+				}
+
+				return age.Epoch.Format(time.RFC3339), nil
+			},
+		},
+		"tleAgeHours": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "hours from the epoch of the satellite's tle to the time of its position",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				age, err := satelliteTLEAge(params)
+				if err != nil {
+					return nil, err
+				}
+
+				return age.AgeHours, nil
+			},
+		},
+		"tleStale": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "the satellite's tle was older than the staleness threshold at the time of its position",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				age, err := satelliteTLEAge(params)
+				if err != nil {
+					return nil, err
+				}
+
+				return age.AgeHours > TLEStaleAfter.Hours(), nil
+			},
+		},
 	},
 })
 
@@ -223,139 +284,164 @@ var SatelliteFeatureCollectionType = graphql.NewObject(graphql.ObjectConfig{
 				return s.Features, nil
 			},
 		},
-	},
-})
-
-// GetTLES creats a map of tles with map of tle lines
-func GetTLES(tle string) map[string]map[string]string {
-	// get pointer to file of tle
-	tlefile, err := os.Open(tle)
-	helpers.PanicErrors(err)
+		"staleness": &graphql.Field{
+			Type:        TLEStalenessSummaryType,
+			Description: "tle epoch ages of the satellites in the collection at the time of their positions",
+			Args: graphql.FieldConfigArgument{
+				"staleAfterHours": &graphql.ArgumentConfig{
+					Type:        graphql.Float,
+					Description: "staleness threshold in hours, defaults to the server's -stale setting",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 
-	// read file into byte slices
-	tlereader := io.Reader(tlefile)
-	tlelines, err := ioutil.ReadAll(tlereader)
-	helpers.PanicErrors(err)
+				s := params.Source.(SatelliteFeatureCollection)
 
-	// convert tle byte slice to string and clean it up
-	tlestringlines := string(tlelines)
-	cleantlelines := strings.Split(tlestringlines, "\n")
+				return resolveStaleness(s.Features, params.Args)
+			},
+		},
+	},
+})
 
-	// Determine if the tle has a title or starts at first satellite
-	if len(cleantlelines[len(cleantlelines)-1]) <= 0 {
-		cleantlelines = cleantlelines[:len(cleantlelines)-1]
-	}
-	if len(cleantlelines)%3 != 0 {
-		cleantlelines = cleantlelines[1:]
+// GetTLES creats a map of tles with map of tle lines, leaving out satellites whose tle fails validation
+func GetTLES(tle string) (map[string]map[string]string, error) {
+	tlelines, err := ioutil.ReadFile(tle)
+	if err != nil {
+		return nil, err
 	}
 
-	// create map of tles sorted by satellite name
-	tlemap := make(map[string]map[string]string, 0)
-	for i := 0; i < len(cleantlelines)/3; i++ {
-		tmpmap := make(map[string]string)
-		tmpmap["firstline"] = strings.Trim(cleantlelines[i*3+1], "\r")
-		tmpmap["secondline"] = strings.Trim(cleantlelines[i*3+2], "\r")
-		o3bname := strings.Trim(cleantlelines[i*3], "\r")
-		o3bnamelen := len(o3bname)
-		name := o3bname[o3bnamelen-4:]
-		tlemap[name] = tmpmap
+	tlemap, report := ParseTLESet(string(tlelines), filepath.Base(tle))
+	RecordIngestReport(report)
+	if len(report.Issues) > 0 {
+		fmt.Println("TLE file", tle, "has", len(report.Issues), "issues, rejected satellites:", report.Rejected)
 	}
 
-	return tlemap
+	return tlemap, nil
 }
 
-// GetBeamplan determines what beamplan file to use based on the satellite being processed
-func GetBeamplan(tlemap map[string]map[string]string, bpfiles map[string]string) {
-	spares := []string{"M002", "M004", "M005"}
-	activenotb3 := []string{"M001", "M003", "M006", "M007", "M008", "M009", "M010", "M011", "M012"}
-	activeb3 := []string{"M013", "M014", "M015", "M016"}
+// GetBeamplan builds the state of every satellite in tlemap from the beamplan file the fleet roster assigns it, or the
+// built-in roster when no ROSTER file was loaded. Satellites missing from the roster or whose beamplan file is missing
+// are skipped
+func GetBeamplan(tlemap map[string]map[string]string, bpfiles map[string]string) (map[string]SatelliteState, error) {
+	roster := GetRoster()
+	if len(roster) == 0 {
+		fmt.Println("WARNING: no fleet roster loaded, falling back to the built-in roster. Add a ROSTER csv like examples/ROSTER.csv to the data directory")
+		roster = builtinRoster(bpfiles)
+	}
 
+	satStates := make(map[string]SatelliteState, 0)
+	bprecords := make(map[string][][]string, 0)
 	for sat, tle := range tlemap {
-		switch true {
-		case helpers.StringInSlice(sat, spares):
-			bpfile := bpfiles["SPARE"]
-			satstate := BuildSatelliteState(bpfile, tle, sat)
-			FillFleetBucket(sat, satstate)
-		case helpers.StringInSlice(sat, activenotb3):
-			switch sat {
-			case "M001":
-				bpfile := bpfiles["M001"]
-				satstate := BuildSatelliteState(bpfile, tle, sat)
-				FillFleetBucket(sat, satstate)
-			default:
-				bpfile := bpfiles["ACTIVE"]
-				satstate := BuildSatelliteState(bpfile, tle, sat)
-				FillFleetBucket(sat, satstate)
+		entry, ok := roster[sat]
+		if !ok {
+			fmt.Println("Satellite", sat, "is not in the fleet roster, skipping")
+			continue
+		}
+		bpfile, ok := bpfiles[entry.Beamplan]
+		if !ok {
+			fmt.Println("Beamplan", entry.Beamplan, "for satellite", sat, "not found, skipping")
+			continue
+		}
+		if _, read := bprecords[bpfile]; !read {
+			header, records, err := ReadCSVFile(bpfile, 0)
+			if err != nil {
+				return nil, err
 			}
-		case helpers.StringInSlice(sat, activeb3):
-			switch sat {
-			case "M013":
-				bpfile := bpfiles["M013"]
-				satstate := BuildSatelliteState(bpfile, tle, sat)
-				FillFleetBucket(sat, satstate)
-			default:
-				bpfile := bpfiles["B3"]
-				satstate := BuildSatelliteState(bpfile, tle, sat)
-				FillFleetBucket(sat, satstate)
+			if len(header) < beamplanColumns {
+				return nil, fmt.Errorf("beamplan %s must have at least %d columns, got %d", entry.Beamplan, beamplanColumns, len(header))
 			}
+			bprecords[bpfile] = records
 		}
+		satStates[sat] = BuildSatelliteState(bprecords[bpfile], tle, sat)
 	}
-	fmt.Println("Fleet bucket filled.")
+
+	return satStates, nil
 }
 
-// FillFleetBucket initializes satellites from tle
-func FillFleetBucket(satid string, satstate SatelliteState) error {
-	satstatebytes, err := json.MarshalIndent(satstate, "", "\t")
-	if err != nil {
-		panic(err)
-	}
-	satstatebytes = bytes.Replace(satstatebytes, []byte("\\u0026"), []byte("&"), -1)
-	satstatebytes = bytes.Trim(satstatebytes, "\r")
+// FillFleetBucket replaces the FLEET bucket with the satellite states built from the data files, archives their tles and
+// redraws the catseyes in one transaction. Changes made through the api survive a reload of the files: a satellite
+// keeps an uploaded tle whose epoch is newer than the file's, and a satellite flying an activated beamplan version
+// keeps that version's missions until another version is activated
+func FillFleetBucket(satStates map[string]SatelliteState) error {
+	err := DB.Update(func(tx *bolt.Tx) error {
+		fleet := tx.Bucket([]byte("DB")).Bucket([]byte("FLEET"))
+		for sat, satstate := range satStates {
+			var current SatelliteState
+			if existing := fleet.Get([]byte(sat)); existing != nil && json.Unmarshal(existing, &current) == nil {
+				currentEpoch, cerr := TLEEpoch(current.TLELine1)
+				fileEpoch, ferr := TLEEpoch(satstate.TLELine1)
+				if cerr == nil && (ferr != nil || currentEpoch.After(fileEpoch)) {
+					satstate.TLELine1, satstate.TLELine2 = current.TLELine1, current.TLELine2
+				}
+			}
+			if missions, ok := activeBeamplanMissions(tx, sat); ok {
+				satstate.Missions = missions
+			}
+			satStates[sat] = satstate
+		}
 
-	err = DB.Update(func(tx *bolt.Tx) error {
-		err = tx.Bucket([]byte("DB")).Bucket([]byte("FLEET")).Put([]byte(satid), satstatebytes)
-		if err != nil {
-			return fmt.Errorf("could not fill zones bucket: %v", err)
+		states := make(map[string]interface{}, 0)
+		for sat, satstate := range satStates {
+			states[sat] = satstate
 		}
-		return nil
+		if err := replaceBucket(tx, "FLEET", states); err != nil {
+			return err
+		}
+		// keep every tle the satellite has flown so past times can be propagated with the elements valid then
+		for sat, satstate := range satStates {
+			if err := archiveTLE(tx, sat, satstate.TLELine1, satstate.TLELine2); err != nil {
+				fmt.Println("Could not archive tle of", sat, ":", err)
+			}
+		}
+
+		// catseyes are drawn for the fleet's mean altitude, so they follow the tles just loaded
+		return fillCatseyes(tx)
 	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Fleet bucket filled.")
 	return nil
 }
 
-// BuildSatelliteState creates Fleet json struct
-func BuildSatelliteState(bpfile string, tle map[string]string, satname string) SatelliteState {
-	// open the beamplan file and pass to a reader
-	bpreader := OpenCSV(bpfile)
-
-	// Get column header values
-	header := getHeader(bpreader)
+// putSatelliteState writes a satellite state to the FLEET bucket and archives its tle
+func putSatelliteState(tx *bolt.Tx, satid string, satstate SatelliteState) error {
+	if err := putFeature(tx, "FLEET", satid, satstate); err != nil {
+		return err
+	}
+	if err := archiveTLE(tx, satid, satstate.TLELine1, satstate.TLELine2); err != nil {
+		fmt.Println("Could not archive tle of", satid, ":", err)
+	}
+	return nil
+}
 
-	msns := make(map[string][][]string, 0)
+// BuildSatelliteState creates Fleet json struct from the rows of a long format beamplan file
+func BuildSatelliteState(records [][]string, tle map[string]string, satname string) SatelliteState {
+	satstate := SatelliteState{
+		TLELine1: tle["firstline"],
+		TLELine2: tle["secondline"],
+		Missions: BuildMissions(records, satname),
+	}
 
-	for {
-		record, err := bpreader.Read()
+	return satstate
+}
 
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			helpers.PanicErrors(err)
-		}
+// BuildMissions groups the long format beamplan records of a satellite into missions
+func BuildMissions(records [][]string, satname string) []BeamplanMission {
+	msns := make(map[string][][]string, 0)
 
+	for _, record := range records {
 		satid := record[0]
 		msnid := record[1]
-		if satid == header[0] {
-			continue
-		} else {
-			switch satname {
-			case satid:
-				if msn, ok := msns[msnid]; ok {
-					tmp := append(msn, record)
-					msns[msnid] = tmp
-				} else {
-					tmp := make([][]string, 0)
-					tmp = append(tmp, record)
-					msns[msnid] = tmp
-				}
+		switch satname {
+		case satid:
+			if msn, ok := msns[msnid]; ok {
+				tmp := append(msn, record)
+				msns[msnid] = tmp
+			} else {
+				tmp := make([][]string, 0)
+				tmp = append(tmp, record)
+				msns[msnid] = tmp
 			}
 		}
 	}
@@ -412,13 +498,7 @@ func BuildSatelliteState(bpfile string, tle map[string]string, satname string) S
 		msnsmap = append(msnsmap, bpmsn)
 	}
 
-	satstate := SatelliteState{
-		TLELine1: tle["firstline"],
-		TLELine2: tle["secondline"],
-		Missions: msnsmap,
-	}
-
-	return satstate
+	return msnsmap
 }
 
 // GetSatellitePosition gets the current satellite position from SATPOS db
@@ -436,20 +516,38 @@ func GetSatellitePosition(s string) SatelliteFeature {
 	return livesat
 }
 
-// UpdateSatPos updates satellite positions using go rout
-func UpdateSatPos(t time.Time, sgp4sats map[string]satellite.Satellite) {
-	for i, sat := range sgp4sats {
-		BuildSatelliteFeature(t, sat, i)
+// UpdateSatPos updates satellite positions and publishes them to subscribers, sorted by id so every tick lists
+// the fleet in the same order
+func UpdateSatPos(t time.Time, orbits map[string]Orbit) {
+	features := make([]SatelliteFeature, 0)
+	for i, sat := range orbits {
+		features = append(features, BuildSatelliteFeature(t, sat, i))
 	}
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].Properties.ID < features[j].Properties.ID
+	})
+	SatelliteTicks.Publish(features)
+}
+
+// BuildSatelliteFeature take a satellite's orbit and propagates it. Then stores it in the SATPOS bucket
+func BuildSatelliteFeature(t time.Time, sat Orbit, id string) SatelliteFeature {
+	satFeature := PropagateSatelliteFeature(t, sat, id)
+
+	FillSatPosBucket(satFeature, id)
+
+	return satFeature
+}
+
+// PropagateSatellite propagates a satellite's orbit to the given time and returns its eci position, eci velocity and greenwich sidereal time
+func PropagateSatellite(t time.Time, orbit Orbit) (satellite.Vector3, satellite.Vector3, float64) {
+	pos, vel, gmst, _ := orbit.Propagate(t)
+
+	return pos, vel, gmst
 }
 
-// BuildSatelliteFeature take a satellite.Satellite struct and propagates it. Then pushes it to SatelliteInMotion channel
-func BuildSatelliteFeature(t time.Time, sat satellite.Satellite, id string) {
-	utc := t.UTC()
-	y, m, d := utc.Date()
-	h, min, sec := utc.Clock()
-	gmst := satellite.GSTimeFromDate(y, int(m), d, h, min, sec)
-	pos, _ := satellite.Propagate(sat, y, int(m), d, h, min, sec)
+// PropagateSatelliteFeature propagates a satellite's orbit to the given time and builds its feature without touching the SATPOS bucket
+func PropagateSatelliteFeature(t time.Time, sat Orbit, id string) SatelliteFeature {
+	pos, _, gmst, source := sat.Propagate(t)
 	alt, vel, latlng := satellite.ECIToLLA(pos, gmst)
 	latlngdeg := satellite.LatLongDeg(latlng)
 
@@ -464,15 +562,17 @@ func BuildSatelliteFeature(t time.Time, sat satellite.Satellite, id string) {
 		Velocity: vel,
 		Altitude: alt,
 		Mission:  currentMissions,
+		Source:   source,
 	}
 
 	satFeature := SatelliteFeature{
 		"Feature",
 		geopoint,
 		props,
+		t,
 	}
 
-	FillSatPosBucket(satFeature, id)
+	return satFeature
 }
 
 // GetCurrentMission gets the current mission from sat state object
@@ -516,6 +616,27 @@ func FillSatPosBucket(s SatelliteFeature, id string) {
 	})
 }
 
+// PruneSatPosBucket removes satellites from the SATPOS bucket that are no longer propagated
+func PruneSatPosBucket(sats map[string]Orbit) {
+	err := DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("SATPOS"))
+		stale := make([][]byte, 0)
+		b.ForEach(func(k, v []byte) error {
+			if _, ok := sats[string(k)]; !ok {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		})
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return fmt.Errorf("could not prune satellite positions bucket: %v", err)
+			}
+		}
+		return nil
+	})
+	helpers.PanicErrors(err)
+}
+
 // GetSatelliteStates pulls the satellite states from the db and converts from json byte to structs
 func GetSatelliteStates() map[string]SatelliteState {
 	satStates := make(map[string]SatelliteState, 0)
@@ -533,14 +654,62 @@ func GetSatelliteStates() map[string]SatelliteState {
 	return satStates
 }
 
-// InitSatelliteSGP4 takes satellite state structs and creates a satellite.Satellite object with sgp4 model initialized
-func InitSatelliteSGP4(satStates map[string]SatelliteState) map[string]satellite.Satellite {
-	sgp4sats := make(map[string]satellite.Satellite, 0)
+// InitSatelliteOrbits takes satellite state structs and initializes each satellite's orbit with sgp4 from the archived tle
+// whose epoch is closest to t, or the satellite state's tle when it has no history, and its OEM ephemeris when the roster selects oem
+func InitSatelliteOrbits(satStates map[string]SatelliteState, t time.Time) map[string]Orbit {
+	roster := GetRoster()
+	orbits := make(map[string]Orbit, 0)
 	for i, sat := range satStates {
-		sgp4sats[i] = satellite.TLEToSat(sat.TLELine1, sat.TLELine2, "wgs84")
+		line1, line2 := sat.TLELine1, sat.TLELine2
+		if archived, ok := GetTLEAt(i, t); ok {
+			line1, line2 = archived.TLELine1, archived.TLELine2
+		}
+		orbits[i] = NewOrbit(i, line1, line2, roster)
+		if roster[i].Source == OrbitSourceOEM && orbits[i].Ephemeris == nil {
+			fmt.Println("Satellite", i, "has no OEM ephemeris loaded, propagating with sgp4")
+		}
+	}
+
+	return orbits
+}
+
+// GetSatelliteState pulls a single satellite state from the FLEET bucket
+func GetSatelliteState(s string) (SatelliteState, bool) {
+	var satstate SatelliteState
+	found := false
+	err := DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("FLEET"))
+		sat := b.Get([]byte(s))
+		if sat != nil {
+			found = true
+			json.Unmarshal(sat, &satstate)
+		}
+		return nil
+	})
+	helpers.PanicErrors(err)
+
+	return satstate, found
+}
+
+// GetSatellitePositionAt propagates a single satellite to the given time from the tle with the closest epoch
+func GetSatellitePositionAt(s string, t time.Time) SatelliteFeature {
+	sat, ok := SatelliteOrbitAt(s, t)
+	if !ok {
+		return SatelliteFeature{}
+	}
+
+	return PropagateSatelliteFeature(t, sat, s)
+}
+
+// GetSatellitesAt propagates every satellite in the FLEET bucket to the given time
+func GetSatellitesAt(t time.Time) []SatelliteFeature {
+	sats := make([]SatelliteFeature, 0)
+	orbits := InitSatelliteOrbits(GetSatelliteStates(), t)
+	for id, sat := range orbits {
+		sats = append(sats, PropagateSatelliteFeature(t, sat, id))
 	}
 
-	return sgp4sats
+	return sats
 }
 
 // GetMovingSatellites returns all the satellites from SATPOS bucket
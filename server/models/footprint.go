@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/graphql-go/graphql"
+)
+
+// FootprintFeature geojson polygon of the ground a satellite can see above an elevation mask
+type FootprintFeature struct {
+	Type       string              `json:"type"`
+	Geometry   AreaGeometry        `json:"geometry"`
+	Properties footprintProperties `json:"properties"`
+}
+
+type footprintProperties struct {
+	ID           string  `json:"id"`
+	Time         string  `json:"time"`
+	MinElevation float64 `json:"minElevation"`
+	Altitude     float64 `json:"altitude"`
+	CentralAngle float64 `json:"centralAngle"`
+}
+
+// FootprintPropsType graphql type for footprint properties
+var FootprintPropsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "FootprintProps",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.String,
+		},
+		"time": &graphql.Field{
+			Type:        graphql.String,
+			Description: "RFC3339 time the satellite was propagated to, empty for the last known position",
+		},
+		"minElevation": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"altitude": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "satellite altitude in kilometers",
+		},
+		"centralAngle": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "earth central angle in degrees from the sub-satellite point to the edge of the footprint",
+		},
+	},
+})
+
+// FootprintType graphql object for footprint features
+var FootprintType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Footprint",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"geometry": &graphql.Field{
+			Type:        AreaGeoType,
+			Description: "footprint polygons, split at the antimeridian",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(FootprintFeature)
+
+				return s.Geometry, nil
+			},
+		},
+		"properties": &graphql.Field{
+			Type:        FootprintPropsType,
+			Description: "footprint properties",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(FootprintFeature)
+
+				return s.Properties, nil
+			},
+		},
+	},
+})
+
+// ComputeFootprintRing builds a closed counterclockwise ring of lng/lat points, one per degree of azimuth,
+// around the sub-satellite point for a satellite at altitude in kilometers and an elevation mask in degrees
+func ComputeFootprintRing(lat float64, lng float64, altitude float64, minElevation float64) [][]float64 {
+	subSatLat := helpers.Degs2Rads(lat)
+	subSatLng := helpers.Degs2Rads(lng)
+	centralAngle := CoverageCentralAngle(helpers.Degs2Rads(minElevation), altitude, wgs84A)
+
+	ring := make([][]float64, 0)
+	for i := 360; i > 0; i-- {
+		az := helpers.Degs2Rads(float64(i % 360))
+		pointLat := math.Asin(math.Sin(subSatLat)*math.Cos(centralAngle) + math.Cos(subSatLat)*math.Sin(centralAngle)*math.Cos(az))
+		pointLng := subSatLng + math.Atan2(math.Sin(az)*math.Sin(centralAngle)*math.Cos(subSatLat), math.Cos(centralAngle)-math.Sin(subSatLat)*math.Sin(pointLat))
+		ring = append(ring, []float64{helpers.Rads2Degs(pointLng), helpers.Rads2Degs(pointLat)})
+	}
+	ring = append(ring, ring[0])
+
+	return ring
+}
+
+// BuildFootprintFeature builds the footprint polygon of a satellite feature from its altitude and an elevation mask
+func BuildFootprintFeature(s SatelliteFeature, minElevation float64, t string) (FootprintFeature, error) {
+	if minElevation < 0 || minElevation >= 90 {
+		return FootprintFeature{}, fmt.Errorf("minElevation must be between 0 and 90 degrees")
+	}
+	if len(s.Geometry.Coordinates) < 2 {
+		return FootprintFeature{}, fmt.Errorf("satellite %s has no position", s.Properties.ID)
+	}
+	lng := s.Geometry.Coordinates[0]
+	lat := s.Geometry.Coordinates[1]
+
+	props := footprintProperties{
+		ID:           s.Properties.ID,
+		Time:         t,
+		MinElevation: minElevation,
+		Altitude:     s.Properties.Altitude,
+		CentralAngle: helpers.Rads2Degs(CoverageCentralAngle(helpers.Degs2Rads(minElevation), s.Properties.Altitude, wgs84A)),
+	}
+
+	footprint := FootprintFeature{
+		"Feature",
+		BuildAreaGeometry(ComputeFootprintRing(lat, lng, s.Properties.Altitude, minElevation)),
+		props,
+	}
+
+	return footprint, nil
+}
+
+// footprintArgs graphql arguments shared by the satellite footprint field and the root footprint query
+func footprintArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"minElevation": &graphql.ArgumentConfig{
+			Type:         graphql.Float,
+			DefaultValue: 0.0,
+			Description:  "elevation mask in degrees",
+		},
+		"at": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "RFC3339 time to propagate the satellite to instead of its last known position",
+		},
+	}
+}
+
+// resolveFootprint builds a satellite's footprint, propagating it first when an at argument is given
+func resolveFootprint(id string, s SatelliteFeature, args map[string]interface{}) (interface{}, error) {
+	at, hasAt, err := getTimeArg(args, "at")
+	if err != nil {
+		return nil, err
+	}
+	minElevation, _ := args["minElevation"].(float64)
+
+	if hasAt {
+		return BuildFootprintFeature(GetSatellitePositionAt(id, at), minElevation, at.UTC().Format(time.RFC3339))
+	}
+
+	return BuildFootprintFeature(s, minElevation, "")
+}
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+)
+
+// GeoJSONFeatureCollection RFC 7946 feature collection served by the /api geojson endpoints
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature RFC 7946 feature with flat properties
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry RFC 7946 geometry. Coordinates hold a position, a ring list or a polygon list depending on the type
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// GeoJSONLayers geojson layers served under /api, keyed by the layer name used in the url
+var GeoJSONLayers = map[string]func() []GeoJSONFeature{
+	"targets":    TargetsGeoJSON,
+	"catseyes":   CatseyesGeoJSON,
+	"zones":      ZonesGeoJSON,
+	"satellites": SatellitesGeoJSON,
+}
+
+// GeoJSONLayerProperties property names the features of each geojson layer can be filtered on
+var GeoJSONLayerProperties = map[string][]string{
+	"targets":    propertyNames(targetProperties{}),
+	"catseyes":   propertyNames(ZoneProperties{}),
+	"zones":      propertyNames(ZoneProperties{}),
+	"satellites": propertyNames(satelliteProperties{}),
+}
+
+// propertyNames json field names of a properties struct, the keys featureProperties can give its features
+func propertyNames(props interface{}) []string {
+	names := make([]string, 0)
+	t := reflect.TypeOf(props)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewGeoJSONFeatureCollection wraps features in an RFC 7946 feature collection
+func NewGeoJSONFeatureCollection(features []GeoJSONFeature) GeoJSONFeatureCollection {
+	return GeoJSONFeatureCollection{"FeatureCollection", features}
+}
+
+// featureProperties flattens a properties struct into a map using its json field names
+func featureProperties(props interface{}) map[string]interface{} {
+	properties := make(map[string]interface{}, 0)
+	propsBytes, _ := json.Marshal(props)
+	json.Unmarshal(propsBytes, &properties)
+	return properties
+}
+
+// TargetsGeoJSON converts the TARGETS bucket into point features
+func TargetsGeoJSON() []GeoJSONFeature {
+	features := make([]GeoJSONFeature, 0)
+	for _, t := range GetTargets() {
+		if len(t.Geometry.Coordinates) < 2 {
+			continue
+		}
+		features = append(features, GeoJSONFeature{
+			Type:       "Feature",
+			ID:         t.Properties.TargetID,
+			Geometry:   GeoJSONGeometry{"Point", []float64{t.Geometry.Coordinates[0], t.Geometry.Coordinates[1]}},
+			Properties: featureProperties(t.Properties),
+		})
+	}
+	sortGeoJSONFeatures(features)
+	return features
+}
+
+// CatseyesGeoJSON converts the CATSEYES bucket into polygon and multipolygon features
+func CatseyesGeoJSON() []GeoJSONFeature {
+	features := make([]GeoJSONFeature, 0)
+	for _, c := range GetCatseyes() {
+		if len(c.Geometry.Polygons) == 0 {
+			continue
+		}
+		features = append(features, GeoJSONFeature{
+			Type:       "Feature",
+			ID:         c.Properties.ZoneID,
+			Geometry:   GeoJSONGeometry{c.Geometry.Type, c.Geometry.Coordinates()},
+			Properties: featureProperties(c.Properties),
+		})
+	}
+	sortGeoJSONFeatures(features)
+	return features
+}
+
+// ZonesGeoJSON converts the ZONES bucket into pole to pole longitude bands, split in two where a zone crosses the antimeridian
+func ZonesGeoJSON() []GeoJSONFeature {
+	features := make([]GeoJSONFeature, 0)
+	for _, z := range GetZones() {
+		start, end := z.Properties.StartLng, z.Properties.EndLng
+		var geometry GeoJSONGeometry
+		if end < start {
+			geometry = GeoJSONGeometry{"MultiPolygon", [][][][]float64{lngBand(start, 180), lngBand(-180, end)}}
+		} else {
+			geometry = GeoJSONGeometry{"Polygon", lngBand(start, end)}
+		}
+		features = append(features, GeoJSONFeature{
+			Type:       "Feature",
+			ID:         z.Properties.ZoneID,
+			Geometry:   geometry,
+			Properties: featureProperties(z.Properties),
+		})
+	}
+	sortGeoJSONFeatures(features)
+	return features
+}
+
+// lngBand counterclockwise polygon covering all latitudes between two longitudes
+func lngBand(west float64, east float64) [][][]float64 {
+	return [][][]float64{{{west, -90}, {east, -90}, {east, 90}, {west, 90}, {west, -90}}}
+}
+
+// SatellitesGeoJSON converts the last known positions in the SATPOS bucket into point features
+func SatellitesGeoJSON() []GeoJSONFeature {
+	features := make([]GeoJSONFeature, 0)
+	for _, s := range GetMovingSatellites() {
+		if len(s.Geometry.Coordinates) < 2 {
+			continue
+		}
+		properties := featureProperties(s.Properties)
+		// missions are nested beam lists, flatten them to ids so GIS tools can filter on them
+		missions := make([]string, 0)
+		for _, m := range s.Properties.Mission {
+			missions = append(missions, m.ID)
+		}
+		properties["mission"] = strings.Join(missions, ",")
+		features = append(features, GeoJSONFeature{
+			Type:       "Feature",
+			ID:         s.Properties.ID,
+			Geometry:   GeoJSONGeometry{"Point", []float64{s.Geometry.Coordinates[0], s.Geometry.Coordinates[1]}},
+			Properties: properties,
+		})
+	}
+	sortGeoJSONFeatures(features)
+	return features
+}
+
+func sortGeoJSONFeatures(features []GeoJSONFeature) {
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].ID < features[j].ID
+	})
+}
+
+// ParseBBox parses an RFC 7946 bbox query parameter, west,south,east,north in degrees.
+// A west edge greater than the east edge selects a box crossing the antimeridian
+func ParseBBox(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox must be west,south,east,north")
+	}
+	bbox := make([]float64, 4)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox value %q is not a number", p)
+		}
+		bbox[i] = v
+	}
+	if bbox[1] > bbox[3] || bbox[1] < -90 || bbox[3] > 90 {
+		return nil, fmt.Errorf("bbox latitudes must be within -90 to 90 with south below north")
+	}
+	if bbox[0] < -180 || bbox[0] > 180 || bbox[2] < -180 || bbox[2] > 180 {
+		return nil, fmt.Errorf("bbox longitudes must be within -180 to 180")
+	}
+
+	return bbox, nil
+}
+
+// ValidateGeoJSONFilters checks that every filter key names a property of the layer's features
+func ValidateGeoJSONFilters(layer string, filters url.Values) error {
+	properties := GeoJSONLayerProperties[layer]
+	keys := make([]string, 0)
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !helpers.StringInSlice(key, properties) {
+			return fmt.Errorf("unknown %s property %q, filter on bbox or one of %s", layer, key, strings.Join(properties, ", "))
+		}
+	}
+	return nil
+}
+
+// FilterGeoJSONFeatures keeps the features that intersect bbox, when given, and match every property filter.
+// Each filter key names a property and matches when the property equals any of the key's values
+func FilterGeoJSONFeatures(features []GeoJSONFeature, bbox []float64, filters url.Values) []GeoJSONFeature {
+	filtered := make([]GeoJSONFeature, 0)
+	for _, f := range features {
+		if bbox != nil && !geometryIntersectsBBox(f.Geometry, bbox) {
+			continue
+		}
+		if !propertiesMatch(f.Properties, filters) {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+	return filtered
+}
+
+func propertiesMatch(properties map[string]interface{}, filters url.Values) bool {
+	for key, values := range filters {
+		property, ok := properties[key]
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, v := range values {
+			if propertyEquals(property, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// propertyEquals compares a property with a query value, numerically when both are numbers
+func propertyEquals(property interface{}, value string) bool {
+	if n, ok := property.(float64); ok {
+		v, err := strconv.ParseFloat(value, 64)
+		return err == nil && n == v
+	}
+	return strings.EqualFold(fmt.Sprint(property), value)
+}
+
+// geometryIntersectsBBox tests the extent of a geometry's positions against a bbox
+func geometryIntersectsBBox(g GeoJSONGeometry, bbox []float64) bool {
+	positions := make([][]float64, 0)
+	switch c := g.Coordinates.(type) {
+	case []float64:
+		positions = append(positions, c)
+	case [][][]float64:
+		for _, ring := range c {
+			positions = append(positions, ring...)
+		}
+	case [][][][]float64:
+		// a multipolygon intersects when any of its polygons does
+		for _, polygon := range c {
+			if geometryIntersectsBBox(GeoJSONGeometry{"Polygon", polygon}, bbox) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(positions) == 0 {
+		return false
+	}
+
+	west, south, east, north := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range positions {
+		west, east = math.Min(west, p[0]), math.Max(east, p[0])
+		south, north = math.Min(south, p[1]), math.Max(north, p[1])
+	}
+	if north < bbox[1] || south > bbox[3] {
+		return false
+	}
+	if bbox[0] > bbox[2] {
+		// the box crosses the antimeridian, so it is the union of west..180 and -180..east
+		return east >= bbox[0] || west <= bbox[2]
+	}
+	return east >= bbox[0] && west <= bbox[2]
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// geojsonFixture targets on either side of the antimeridian, a zone band crossing it and one far from it
+func geojsonFixture() []GeoJSONFeature {
+	point := func(id string, lng float64, lat float64, gateway string, aos float64) GeoJSONFeature {
+		return GeoJSONFeature{"Feature", id, GeoJSONGeometry{"Point", []float64{lng, lat}},
+			map[string]interface{}{"targetID": id, "gatewayFlag": gateway, "minElTlmAOS": aos}}
+	}
+	return []GeoJSONFeature{
+		point("FIJI", 178.4, -18.1, "1", 5),
+		point("SAMOA", -171.8, -13.8, "0", 10),
+		point("GREENWICH", 0, 51.5, "1", 5),
+		point("HAWAII", -157.9, 21.3, "1", 7.5),
+		{"Feature", "Z180", GeoJSONGeometry{"MultiPolygon", [][][][]float64{lngBand(170, 180), lngBand(-180, -175)}},
+			map[string]interface{}{"zoneid": "Z180", "gateway": "FIJI"}},
+		{"Feature", "Z000", GeoJSONGeometry{"Polygon", lngBand(-5, 5)}, map[string]interface{}{"zoneid": "Z000", "gateway": "GREENWICH"}},
+	}
+}
+
+func geojsonIDs(features []GeoJSONFeature) []string {
+	ids := make([]string, 0)
+	for _, f := range features {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func TestParseBBox(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []float64
+	}{
+		{"box", "-10,-20,30,40", []float64{-10, -20, 30, 40}},
+		{"box crossing 180", "170, -30, -170, 30", []float64{170, -30, -170, 30}},
+		{"whole world", "-180,-90,180,90", []float64{-180, -90, 180, 90}},
+		{"three values", "1,2,3", nil},
+		{"not a number", "a,2,3,4", nil},
+		{"south above north", "0,10,10,0", nil},
+		{"latitude past the pole", "0,-91,10,0", nil},
+		{"longitude past 180", "170,0,190,10", nil},
+		{"longitude past -180", "-190,0,-170,10", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBBox(tt.s)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: ParseBBox(%q) = %v, want an error", tt.name, tt.s, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseBBox(%q) = %v, %v, want %v", tt.name, tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestFilterGeoJSONFeatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		bbox    []float64
+		filters url.Values
+		want    []string
+	}{
+		{"no filter", nil, url.Values{}, []string{"FIJI", "SAMOA", "GREENWICH", "HAWAII", "Z180", "Z000"}},
+		{"box crossing 180", []float64{170, -30, -170, 30}, url.Values{}, []string{"FIJI", "SAMOA", "Z180"}},
+		{"box crossing 180 to the north", []float64{170, 0, -150, 30}, url.Values{}, []string{"HAWAII", "Z180"}},
+		{"box east of 180 only", []float64{-175, -30, -170, 0}, url.Values{}, []string{"SAMOA", "Z180"}},
+		{"box west of 180 only", []float64{175, -30, 179, 0}, url.Values{}, []string{"FIJI", "Z180"}},
+		{"box at greenwich", []float64{-1, 50, 1, 52}, url.Values{}, []string{"GREENWICH", "Z000"}},
+		{"box between the features", []float64{20, -30, 160, 30}, url.Values{}, []string{}},
+		{"gateway flag", nil, url.Values{"gatewayFlag": {"1"}}, []string{"FIJI", "GREENWICH", "HAWAII"}},
+		{"any of several values", nil, url.Values{"targetID": {"fiji", "hawaii"}}, []string{"FIJI", "HAWAII"}},
+		{"number compared numerically", nil, url.Values{"minElTlmAOS": {"7.50"}}, []string{"HAWAII"}},
+		{"every filter must match", nil, url.Values{"gatewayFlag": {"1"}, "minElTlmAOS": {"5"}}, []string{"FIJI", "GREENWICH"}},
+		{"box crossing 180 and a filter", []float64{170, -30, -170, 30}, url.Values{"gatewayFlag": {"0"}}, []string{"SAMOA"}},
+		{"zone property", nil, url.Values{"gateway": {"FIJI"}}, []string{"Z180"}},
+		{"no match", nil, url.Values{"gatewayFlag": {"2"}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		got := geojsonIDs(FilterGeoJSONFeatures(geojsonFixture(), tt.bbox, tt.filters))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: FilterGeoJSONFeatures = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateGeoJSONFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		layer   string
+		filters url.Values
+		ok      bool
+	}{
+		{"target property", "targets", url.Values{"gatewayFlag": {"1"}}, true},
+		{"zone property", "zones", url.Values{"gateway": {"FIJI"}, "startlng": {"170"}}, true},
+		{"satellite property", "satellites", url.Values{"source": {"oem"}}, true},
+		{"no filters", "catseyes", url.Values{}, true},
+		{"zone property on targets", "targets", url.Values{"gateway": {"FIJI"}}, false},
+		{"misspelled property", "satellites", url.Values{"sorce": {"oem"}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateGeoJSONFilters(tt.layer, tt.filters); (err == nil) != tt.ok {
+			t.Errorf("%s: ValidateGeoJSONFilters(%s, %v) = %v, want ok %v", tt.name, tt.layer, tt.filters, err, tt.ok)
+		}
+	}
+}
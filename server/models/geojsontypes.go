@@ -1,30 +1,79 @@
 package models
 
 import (
+	"encoding/json"
+
 	"github.com/graphql-go/graphql"
 )
 
-// PolygonGeometry struct that models geojson geometry type for polygons
-type PolygonGeometry struct {
-	Type        string      `json:"type"`
-	Coordinates [][]float64 `json:"coordinates"`
+// AreaGeometry RFC 7946 Polygon or MultiPolygon. Polygons holds each polygon as closed counterclockwise lng/lat rings
+// and is written as the coordinates of a Polygon when there is one polygon and of a MultiPolygon otherwise
+type AreaGeometry struct {
+	Type     string
+	Polygons [][][][]float64
+}
+
+// NewAreaGeometry picks Polygon or MultiPolygon from the number of polygons
+func NewAreaGeometry(polygons [][][][]float64) AreaGeometry {
+	if len(polygons) == 1 {
+		return AreaGeometry{"Polygon", polygons}
+	}
+	return AreaGeometry{"MultiPolygon", polygons}
+}
+
+// Coordinates geojson coordinates of the geometry
+func (g AreaGeometry) Coordinates() interface{} {
+	if g.Type == "Polygon" && len(g.Polygons) == 1 {
+		return g.Polygons[0]
+	}
+	return g.Polygons
+}
+
+// MarshalJSON writes the geometry as a geojson geometry object
+func (g AreaGeometry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{g.Type, g.Coordinates()})
 }
 
-// PolyGeoType graphql object for individual catseye queries
-var PolyGeoType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "polygonGeometry",
+// UnmarshalJSON reads a geojson Polygon or MultiPolygon geometry object
+func (g *AreaGeometry) UnmarshalJSON(b []byte) error {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(b, &geometry); err != nil {
+		return err
+	}
+	g.Type = geometry.Type
+	if geometry.Type == "Polygon" {
+		var polygon [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return err
+		}
+		g.Polygons = [][][][]float64{polygon}
+		return nil
+	}
+	return json.Unmarshal(geometry.Coordinates, &g.Polygons)
+}
+
+// AreaGeoType graphql object for polygon and multipolygon geometries
+var AreaGeoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "areaGeometry",
 	Fields: graphql.Fields{
 		"type": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Polygon, or MultiPolygon when the area is split at the antimeridian",
 		},
-		"coordinates": &graphql.Field{
-			Type:        graphql.NewList(graphql.NewList(graphql.Float)),
-			Description: "List of coordinates to build shape",
+		"polygons": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewList(graphql.NewList(graphql.NewList(graphql.Float)))),
+			Description: "List of polygons, each a list of closed counterclockwise lng/lat rings",
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 
-				s := params.Source.(PolygonGeometry)
+				s := params.Source.(AreaGeometry)
 
-				return s.Coordinates, nil
+				return s.Polygons, nil
 			},
 		},
 	},
@@ -38,7 +87,7 @@ type PointGeometry struct {
 
 // PointGeoType graphql object for individual beamplan mission queries
 var PointGeoType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "polygonGeometry",
+	Name: "pointGeometry",
 	Fields: graphql.Fields{
 		"type": &graphql.Field{
 			Type: graphql.String,
@@ -55,3 +104,29 @@ var PointGeoType = graphql.NewObject(graphql.ObjectConfig{
 		},
 	},
 })
+
+// MultiLineGeometry struct that models geojson geometry type for multi line strings
+type MultiLineGeometry struct {
+	Type        string        `json:"type"`
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+// MultiLineGeoType graphql object for line geometries such as ground tracks
+var MultiLineGeoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "multiLineGeometry",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"coordinates": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewList(graphql.NewList(graphql.Float))),
+			Description: "List of line strings, each a list of coordinates",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(MultiLineGeometry)
+
+				return s.Coordinates, nil
+			},
+		},
+	},
+})
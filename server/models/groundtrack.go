@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+// MaxGroundTrackPoints upper bound on the number of propagated vertices in a single ground track
+const MaxGroundTrackPoints = 20000
+
+// GroundTrackFeature geojson structure for a satellite ground track over a time window
+type GroundTrackFeature struct {
+	Type       string                `json:"type"`
+	Geometry   MultiLineGeometry     `json:"geometry"`
+	Properties groundTrackProperties `json:"properties"`
+}
+
+type groundTrackProperties struct {
+	ID    string     `json:"id"`
+	Start string     `json:"start"`
+	End   string     `json:"end"`
+	Step  int        `json:"step"`
+	Times [][]string `json:"times"`
+}
+
+// GroundTrackPropsType graphql type for ground track properties
+var GroundTrackPropsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "GroundTrackProps",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.String,
+		},
+		"start": &graphql.Field{
+			Type: graphql.String,
+		},
+		"end": &graphql.Field{
+			Type: graphql.String,
+		},
+		"step": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"times": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewList(graphql.String)),
+			Description: "RFC3339 timestamps matching each vertex of each line string",
+		},
+	},
+})
+
+// GroundTrackType graphql object for ground track features
+var GroundTrackType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "GroundTrack",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"geometry": &graphql.Field{
+			Type:        MultiLineGeoType,
+			Description: "ground track split at the antimeridian",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(GroundTrackFeature)
+
+				return s.Geometry, nil
+			},
+		},
+		"properties": &graphql.Field{
+			Type:        GroundTrackPropsType,
+			Description: "ground track properties",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(GroundTrackFeature)
+
+				return s.Properties, nil
+			},
+		},
+	},
+})
+
+// TrackPoint single propagated sub-satellite point
+type TrackPoint struct {
+	Time      time.Time
+	Longitude float64
+	Latitude  float64
+	Altitude  float64
+}
+
+// PropagateTrack propagates a satellite from start to end every step and returns its sub-satellite points
+func PropagateTrack(sat Orbit, start time.Time, end time.Time, step time.Duration) ([]TrackPoint, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+	if int(end.Sub(start)/step)+1 > MaxGroundTrackPoints {
+		return nil, fmt.Errorf("window would produce more than %d points, use a larger step", MaxGroundTrackPoints)
+	}
+
+	points := make([]TrackPoint, 0)
+	for t := start; !t.After(end); t = t.Add(step) {
+		pos, _, gmst := PropagateSatellite(t, sat)
+		alt, _, latlng := satellite.ECIToLLA(pos, gmst)
+		latlngdeg := satellite.LatLongDeg(latlng)
+		points = append(points, TrackPoint{t, latlngdeg.Longitude, latlngdeg.Latitude, alt})
+	}
+
+	return points, nil
+}
+
+// BuildGroundTrackFeature propagates the satellite in the FLEET bucket across the window and builds its ground track
+func BuildGroundTrackFeature(id string, start time.Time, end time.Time, step time.Duration) (GroundTrackFeature, error) {
+	sat, ok := SatelliteOrbitOver(id, start, end)
+	if !ok {
+		return GroundTrackFeature{}, fmt.Errorf("satellite %s not found", id)
+	}
+
+	points, err := PropagateTrack(sat, start, end, step)
+	if err != nil {
+		return GroundTrackFeature{}, err
+	}
+
+	lines, times := SplitTrackAtAntimeridian(points)
+	timestrings := make([][]string, 0)
+	for _, segment := range times {
+		tmp := make([]string, 0)
+		for _, t := range segment {
+			tmp = append(tmp, t.UTC().Format(time.RFC3339))
+		}
+		timestrings = append(timestrings, tmp)
+	}
+
+	props := groundTrackProperties{
+		ID:    id,
+		Start: start.UTC().Format(time.RFC3339),
+		End:   end.UTC().Format(time.RFC3339),
+		Step:  int(step / time.Second),
+		Times: timestrings,
+	}
+
+	track := GroundTrackFeature{
+		"Feature",
+		MultiLineGeometry{"MultiLineString", lines},
+		props,
+	}
+
+	return track, nil
+}
+
+// SplitTrackAtAntimeridian breaks a track into lng/lat line strings wherever it crosses +/-180 degrees, interpolating the crossing point and time
+func SplitTrackAtAntimeridian(points []TrackPoint) ([][][]float64, [][]time.Time) {
+	lines := make([][][]float64, 0)
+	times := make([][]time.Time, 0)
+	if len(points) == 0 {
+		return lines, times
+	}
+
+	line := [][]float64{{points[0].Longitude, points[0].Latitude}}
+	linetimes := []time.Time{points[0].Time}
+	for i := 1; i < len(points); i++ {
+		prev := points[i-1]
+		cur := points[i]
+
+		if math.Abs(cur.Longitude-prev.Longitude) > 180.0 {
+			// unwrap the current longitude next to the previous one and find where the segment meets the antimeridian
+			edge := 180.0
+			curlng := cur.Longitude + 360.0
+			if prev.Longitude < 0 {
+				edge = -180.0
+				curlng = cur.Longitude - 360.0
+			}
+			f := (edge - prev.Longitude) / (curlng - prev.Longitude)
+			lat := prev.Latitude + f*(cur.Latitude-prev.Latitude)
+			crossing := prev.Time.Add(time.Duration(f * float64(cur.Time.Sub(prev.Time))))
+
+			line = append(line, []float64{edge, lat})
+			linetimes = append(linetimes, crossing)
+			lines = append(lines, line)
+			times = append(times, linetimes)
+
+			line = [][]float64{{-edge, lat}}
+			linetimes = []time.Time{crossing}
+		}
+
+		line = append(line, []float64{cur.Longitude, cur.Latitude})
+		linetimes = append(linetimes, cur.Time)
+	}
+	lines = append(lines, line)
+	times = append(times, linetimes)
+
+	return lines, times
+}
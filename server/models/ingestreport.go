@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// TLEIssue problem found on a line of tle text
+type TLEIssue struct {
+	Line        int    `json:"line"`
+	SatelliteID string `json:"satelliteID"`
+	Field       string `json:"field"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+}
+
+// TLEIssueType graphql object for tle issues
+var TLEIssueType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TLEIssue",
+	Fields: graphql.Fields{
+		"line": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "1 based line of the tle text",
+		},
+		"satelliteId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"field": &graphql.Field{
+			Type: graphql.String,
+		},
+		"severity": &graphql.Field{
+			Type:        graphql.String,
+			Description: "error when the satellite's tle was rejected, warning otherwise",
+		},
+		"message": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+// IngestReport result of validating a tle file or upload
+type IngestReport struct {
+	Source   string     `json:"source"`
+	Time     string     `json:"time"`
+	Accepted []string   `json:"accepted"`
+	Rejected []string   `json:"rejected"`
+	Issues   []TLEIssue `json:"issues"`
+}
+
+// IngestReportType graphql object for tle ingest reports
+var IngestReportType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "IngestReport",
+	Fields: graphql.Fields{
+		"source": &graphql.Field{
+			Type:        graphql.String,
+			Description: "ephemeris file name, or upload for tles sent through uploadTLE",
+		},
+		"time": &graphql.Field{
+			Type: graphql.String,
+		},
+		"accepted": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"rejected": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"issues": &graphql.Field{
+			Type: graphql.NewList(TLEIssueType),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(IngestReport)
+
+				return s.Issues, nil
+			},
+		},
+	},
+})
+
+// ingestReports latest tle ingest report
+type ingestReports struct {
+	mu     sync.RWMutex
+	latest *IngestReport
+}
+
+var lastIngest = &ingestReports{}
+
+// RecordIngestReport keeps a report as the latest tle ingest report
+func RecordIngestReport(report IngestReport) {
+	lastIngest.mu.Lock()
+	lastIngest.latest = &report
+	lastIngest.mu.Unlock()
+}
+
+// GetIngestReport returns the latest tle ingest report and whether any tles have been ingested
+func GetIngestReport() (IngestReport, bool) {
+	lastIngest.mu.RLock()
+	defer lastIngest.mu.RUnlock()
+	if lastIngest.latest == nil {
+		return IngestReport{}, false
+	}
+
+	return *lastIngest.latest, true
+}
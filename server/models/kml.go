@@ -0,0 +1,258 @@
+package models
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// KML document of targets, catseyes and satellite ground tracks for Google Earth
+type KML struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	XmlnsGX  string      `xml:"xmlns:gx,attr"`
+	Document KMLDocument `xml:"Document"`
+}
+
+// KMLDocument named document holding the shared styles and one folder per kind of feature
+type KMLDocument struct {
+	Name    string      `xml:"name"`
+	Styles  []KMLStyle  `xml:"Style"`
+	Folders []KMLFolder `xml:"Folder"`
+}
+
+// KMLStyle style referenced by placemarks through their styleUrl
+type KMLStyle struct {
+	ID        string        `xml:"id,attr"`
+	IconStyle *KMLIconStyle `xml:"IconStyle,omitempty"`
+	LineStyle *KMLLineStyle `xml:"LineStyle,omitempty"`
+	PolyStyle *KMLPolyStyle `xml:"PolyStyle,omitempty"`
+}
+
+// KMLIconStyle icon color in aabbggrr hex and scale
+type KMLIconStyle struct {
+	Color string  `xml:"color"`
+	Scale float64 `xml:"scale"`
+	Href  string  `xml:"Icon>href"`
+}
+
+// KMLLineStyle line color in aabbggrr hex and width in pixels
+type KMLLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+// KMLPolyStyle polygon fill color in aabbggrr hex
+type KMLPolyStyle struct {
+	Color   string `xml:"color"`
+	Outline int    `xml:"outline"`
+}
+
+// KMLFolder folder of placemarks
+type KMLFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []KMLPlacemark `xml:"Placemark"`
+}
+
+// KMLPlacemark placemark holding exactly one of a point, polygon, multi geometry or track
+type KMLPlacemark struct {
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description,omitempty"`
+	StyleURL      string            `xml:"styleUrl"`
+	Point         *KMLPoint         `xml:"Point,omitempty"`
+	Polygon       *KMLPolygon       `xml:"Polygon,omitempty"`
+	MultiGeometry *KMLMultiGeometry `xml:"MultiGeometry,omitempty"`
+	Track         *KMLTrack         `xml:"gx:Track,omitempty"`
+}
+
+// KMLPoint point coordinates as lng,lat,altitude in degrees and meters
+type KMLPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// KMLPolygon polygon with a single closed outer ring
+type KMLPolygon struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+// KMLMultiGeometry polygons of an area split at the antimeridian
+type KMLMultiGeometry struct {
+	Polygons []KMLPolygon `xml:"Polygon"`
+}
+
+// KMLTrack time stamped track, each when matching the gx:coord at the same index
+type KMLTrack struct {
+	AltitudeMode string   `xml:"altitudeMode"`
+	When         []string `xml:"when"`
+	Coords       []string `xml:"gx:coord"`
+}
+
+// kml styles of targets by their flags, catseyes and ground tracks. Colors are aabbggrr
+var kmlStyles = []KMLStyle{
+	{ID: "target", IconStyle: &KMLIconStyle{"ffffc800", 1.0, "http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png"}},
+	{ID: "gateway", IconStyle: &KMLIconStyle{"ff5a5aff", 1.2, "http://maps.google.com/mapfiles/kml/shapes/triangle.png"}},
+	{ID: "ttc", IconStyle: &KMLIconStyle{"ff00d6ff", 1.2, "http://maps.google.com/mapfiles/kml/shapes/square.png"}},
+	{ID: "gateway-ttc", IconStyle: &KMLIconStyle{"ff0080ff", 1.4, "http://maps.google.com/mapfiles/kml/shapes/star.png"}},
+	{ID: "catseye", LineStyle: &KMLLineStyle{"ff80ff00", 1.5}, PolyStyle: &KMLPolyStyle{"4080ff00", 1}},
+	{ID: "groundtrack", LineStyle: &KMLLineStyle{"ff00d6ff", 2}, IconStyle: &KMLIconStyle{"ff00d6ff", 1.0, "http://maps.google.com/mapfiles/kml/shapes/track.png"}},
+}
+
+// targetStyle picks the placemark style of a target from its gateway and ttc flags
+func targetStyle(t TargetFeature) string {
+	gateway := TargetFlagSet(t.Properties.GatewayFlag)
+	ttc := TargetFlagSet(t.Properties.TTCFlag)
+	switch {
+	case gateway && ttc:
+		return "#gateway-ttc"
+	case gateway:
+		return "#gateway"
+	case ttc:
+		return "#ttc"
+	default:
+		return "#target"
+	}
+}
+
+// BuildKML builds a KML document of the TARGETS targets and the CATSEYES polygons. When tracks is true the ground track of
+// every FLEET satellite is added, sampled from start to end every step
+func BuildKML(start time.Time, end time.Time, step time.Duration, tracks bool) (KML, error) {
+	targets := KMLFolder{Name: "Targets", Placemarks: make([]KMLPlacemark, 0)}
+	targetFeatures := GetTargets()
+	sort.Slice(targetFeatures, func(i, j int) bool {
+		return targetFeatures[i].Properties.TargetID < targetFeatures[j].Properties.TargetID
+	})
+	for _, t := range targetFeatures {
+		if len(t.Geometry.Coordinates) < 2 {
+			continue
+		}
+		targets.Placemarks = append(targets.Placemarks, KMLPlacemark{
+			Name:        t.Properties.ShortName,
+			Description: fmt.Sprintf("%s (%s) gateway: %s, ttc: %s", t.Properties.LongName, t.Properties.TargetID, t.Properties.GatewayFlag, t.Properties.TTCFlag),
+			StyleURL:    targetStyle(t),
+			Point: &KMLPoint{
+				Coordinates: fmt.Sprintf("%g,%g,%s", t.Geometry.Coordinates[0], t.Geometry.Coordinates[1], kmlAltitude(t.Properties.Altitude)),
+			},
+		})
+	}
+
+	catseyes := KMLFolder{Name: "Catseyes", Placemarks: make([]KMLPlacemark, 0)}
+	catseyeFeatures := GetCatseyes()
+	sort.Slice(catseyeFeatures, func(i, j int) bool {
+		return catseyeFeatures[i].Properties.ZoneID < catseyeFeatures[j].Properties.ZoneID
+	})
+	for _, c := range catseyeFeatures {
+		if len(c.Geometry.Polygons) == 0 {
+			continue
+		}
+		polygons := make([]KMLPolygon, 0)
+		for _, polygon := range c.Geometry.Polygons {
+			ring := ""
+			for _, p := range polygon[0] {
+				ring = ring + fmt.Sprintf("%g,%g,0 ", p[0], p[1])
+			}
+			polygons = append(polygons, KMLPolygon{Tessellate: 1, Coordinates: ring})
+		}
+		placemark := KMLPlacemark{
+			Name:        c.Properties.ZoneID,
+			Description: fmt.Sprintf("subregion: %s, gateway: %s", c.Properties.Subregion, c.Properties.Gateway),
+			StyleURL:    "#catseye",
+		}
+		if len(polygons) == 1 {
+			placemark.Polygon = &polygons[0]
+		} else {
+			placemark.MultiGeometry = &KMLMultiGeometry{polygons}
+		}
+		catseyes.Placemarks = append(catseyes.Placemarks, placemark)
+	}
+
+	folders := []KMLFolder{targets, catseyes}
+	if tracks {
+		groundtracks, err := buildKMLTracks(start, end, step)
+		if err != nil {
+			return KML{}, err
+		}
+		folders = append(folders, groundtracks)
+	}
+
+	return KML{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGX: "http://www.google.com/kml/ext/2.2",
+		Document: KMLDocument{
+			Name:    "worldmap",
+			Styles:  kmlStyles,
+			Folders: folders,
+		},
+	}, nil
+}
+
+// buildKMLTracks propagates every FLEET satellite across the window into time stamped tracks
+func buildKMLTracks(start time.Time, end time.Time, step time.Duration) (KMLFolder, error) {
+	if !end.After(start) {
+		return KMLFolder{}, fmt.Errorf("end must be after start")
+	}
+	folder := KMLFolder{
+		Name:       fmt.Sprintf("Ground tracks %s to %s", start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)),
+		Placemarks: make([]KMLPlacemark, 0),
+	}
+
+	ids := make([]string, 0)
+	for id := range GetSatelliteStates() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		sat, ok := SatelliteOrbitOver(id, start, end)
+		if !ok {
+			continue
+		}
+		points, err := PropagateTrack(sat, start, end, step)
+		if err != nil {
+			return KMLFolder{}, err
+		}
+
+		track := &KMLTrack{AltitudeMode: "absolute", When: make([]string, 0), Coords: make([]string, 0)}
+		for _, p := range points {
+			track.When = append(track.When, p.Time.UTC().Format(time.RFC3339))
+			track.Coords = append(track.Coords, fmt.Sprintf("%g %g %g", p.Longitude, p.Latitude, p.Altitude*1000))
+		}
+		folder.Placemarks = append(folder.Placemarks, KMLPlacemark{
+			Name:     id,
+			StyleURL: "#groundtrack",
+			Track:    track,
+		})
+	}
+
+	return folder, nil
+}
+
+// kmlAltitude formats a target's altitude column in meters, 0 when it is empty or not a number
+func kmlAltitude(s string) string {
+	return fmt.Sprintf("%g", convertStringToFloat64(s))
+}
+
+// WriteKML writes the document as indented KML
+func WriteKML(w io.Writer, k KML) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(k)
+}
+
+// WriteKMZ writes the document zipped as doc.kml, the layout Google Earth expects of a KMZ file
+func WriteKMZ(w io.Writer, k KML) error {
+	archive := zip.NewWriter(w)
+	doc, err := archive.Create("doc.kml")
+	if err != nil {
+		return err
+	}
+	if err := WriteKML(doc, k); err != nil {
+		return err
+	}
+	return archive.Close()
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"sync"
+)
+
+// SatelliteOrbits set of initialized satellite orbits read by the propagation loop on every tick
+type SatelliteOrbits struct {
+	mu     sync.RWMutex
+	orbits map[string]Orbit
+}
+
+// LiveSatellites satellites currently propagated into the SATPOS bucket
+var LiveSatellites = &SatelliteOrbits{
+	orbits: make(map[string]Orbit, 0),
+}
+
+// Load returns the current satellites; the returned map must not be modified
+func (s *SatelliteOrbits) Load() map[string]Orbit {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.orbits
+}
+
+// Store swaps in a new set of satellites for the next tick
+func (s *SatelliteOrbits) Store(orbits map[string]Orbit) {
+	s.mu.Lock()
+	s.orbits = orbits
+	s.mu.Unlock()
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+)
+
+// RootMutation main graphql mutation for schema, every field of which needs an authorized request
+var RootMutation = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RootMutation",
+	Fields: requireAuthorization(graphql.Fields{
+		"uploadTLE": &graphql.Field{
+			Type:        TLEUploadResultType,
+			Description: "Replace the tle of every satellite in a 3 line element set or CCSDS OMM and start propagating the new elements. Reloading the data files keeps an uploaded tle until a file brings a newer epoch",
+			Args: graphql.FieldConfigArgument{
+				"text": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				text, _ := params.Args["text"].(string)
+
+				return UploadTLE(text)
+			},
+		},
+		"uploadBeamplan": &graphql.Field{
+			Type:        BeamplanVersionType,
+			Description: "Validate a long format beamplan csv and store it as a new inactive version",
+			Args: graphql.FieldConfigArgument{
+				"file": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "contents of the BEAMPLAN_LONGFORMAT csv",
+				},
+				"satellites": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.String)),
+					Description: "satellites the beamplan is for",
+				},
+				"effectiveFrom": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 time the beamplan is meant to take effect",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				effectiveFrom, hasEffectiveFrom, err := getTimeArg(params.Args, "effectiveFrom")
+				if err != nil {
+					return nil, err
+				}
+				if !hasEffectiveFrom {
+					return nil, fmt.Errorf("effectiveFrom is required")
+				}
+				file, _ := params.Args["file"].(string)
+				satellites := make([]string, 0)
+				satArgs, _ := params.Args["satellites"].([]interface{})
+				for _, sat := range satArgs {
+					if s, isOK := sat.(string); isOK {
+						satellites = append(satellites, s)
+					}
+				}
+
+				return UploadBeamplan(file, satellites, effectiveFrom)
+			},
+		},
+		"activateBeamplan": &graphql.Field{
+			Type:        BeamplanVersionType,
+			Description: "Rebuild the missions of the beamplan's satellites from an uploaded beamplan version once its effectiveFrom time has passed. Nothing is changed when any satellite fails validation. Reloading the data files keeps these missions until another version is activated",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+
+				return ActivateBeamplan(id)
+			},
+		},
+		"createTarget": &graphql.Field{
+			Type:        TargetType,
+			Description: "Add a target to the TARGETS bucket",
+			Args:        targetArgs(true),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				changes := columnArgChanges(params.Args, targetColumns)
+				r := make([]string, len(targetColumns))
+				for i, column := range targetColumns {
+					r[i] = changes[column]
+				}
+
+				return CreateTarget(r)
+			},
+		},
+		"updateTarget": &graphql.Field{
+			Type:        TargetType,
+			Description: "Change the given fields of a target in the TARGETS bucket",
+			Args:        targetArgs(false),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+
+				return UpdateTarget(id, columnArgChanges(params.Args, targetColumns))
+			},
+		},
+		"deleteTarget": &graphql.Field{
+			Type:        TargetType,
+			Description: "Remove a target from the TARGETS bucket",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+
+				return DeleteTarget(id)
+			},
+		},
+		"createZone": &graphql.Field{
+			Type:        CatseyeType,
+			Description: "Add a zone to the ZONES bucket and return its catseye",
+			Args:        zoneArgs(true),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				changes := columnArgChanges(params.Args, zoneColumns)
+				r := make([]string, len(zoneColumns))
+				for i, column := range zoneColumns {
+					r[i] = changes[column]
+				}
+
+				return CreateZone(r)
+			},
+		},
+		"updateZone": &graphql.Field{
+			Type:        CatseyeType,
+			Description: "Change the given fields of a zone in the ZONES bucket and return its recomputed catseye",
+			Args:        zoneArgs(false),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+
+				return UpdateZone(id, columnArgChanges(params.Args, zoneColumns))
+			},
+		},
+		"deleteZone": &graphql.Field{
+			Type:        CatseyeType,
+			Description: "Remove a zone and its catseye",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+
+				return DeleteZone(id)
+			},
+		},
+	}),
+})
+
+// authorizedKey context key the graphql handler sets when a request carries the server's bearer token
+type authorizedKey struct{}
+
+// WithAuthorization marks a request context as allowed, or not, to run mutations
+func WithAuthorization(ctx context.Context, authorized bool) context.Context {
+	return context.WithValue(ctx, authorizedKey{}, authorized)
+}
+
+// requireAuthorization refuses every field unless the request context was marked authorized
+func requireAuthorization(fields graphql.Fields) graphql.Fields {
+	for name, field := range fields {
+		name, resolve := name, field.Resolve
+		field.Resolve = func(params graphql.ResolveParams) (interface{}, error) {
+			if authorized, _ := params.Context.Value(authorizedKey{}).(bool); !authorized {
+				return nil, fmt.Errorf("%s needs the server's token as a bearer Authorization header", name)
+			}
+			return resolve(params)
+		}
+	}
+	return fields
+}
+
+// targetArgs graphql arguments for the columns of a target, with coordinates required when creating
+func targetArgs(create bool) graphql.FieldConfigArgument {
+	coordinate := graphql.Input(graphql.Float)
+	if create {
+		coordinate = graphql.NewNonNull(graphql.Float)
+	}
+
+	return graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"shortName": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"latitude": &graphql.ArgumentConfig{
+			Type: coordinate,
+		},
+		"longitude": &graphql.ArgumentConfig{
+			Type:        coordinate,
+			Description: "degrees east, values above 180 are normalized to -180 to 180",
+		},
+		"altitude": &graphql.ArgumentConfig{
+			Type:        graphql.Float,
+			Description: "altitude in meters",
+		},
+		"gatewayFlag": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"ttcFlag": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"minElTlmAOS": &graphql.ArgumentConfig{
+			Type: graphql.Float,
+		},
+		"minElTlmLOS": &graphql.ArgumentConfig{
+			Type:        graphql.Float,
+			Description: "must not be above minElTlmAOS",
+		},
+		"longName": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"fileCode": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"minElGateway": &graphql.ArgumentConfig{
+			Type:        graphql.Float,
+			Description: "elevation mask in degrees the target's zones are drawn with when it is their gateway",
+		},
+	}
+}
+
+// zoneArgs graphql arguments for the columns of a zone, with longitudes required when creating
+func zoneArgs(create bool) graphql.FieldConfigArgument {
+	longitude := graphql.Input(graphql.Float)
+	if create {
+		longitude = graphql.NewNonNull(graphql.Float)
+	}
+
+	return graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"subregion": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+		"startLng": &graphql.ArgumentConfig{
+			Type:        longitude,
+			Description: "western edge of the zone, values above 180 are normalized to -180 to 180",
+		},
+		"centerLng": &graphql.ArgumentConfig{
+			Type: longitude,
+		},
+		"endLng": &graphql.ArgumentConfig{
+			Type:        longitude,
+			Description: "eastern edge of the zone, may be west of startLng when the zone crosses the antimeridian",
+		},
+		"gateway": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "id or short name of the gateway target, whose minElGateway masks the catseye",
+		},
+		"altitude": &graphql.ArgumentConfig{
+			Type:        graphql.Float,
+			Description: "satellite altitude in kilometers to draw the catseye for instead of the fleet's mean tle altitude",
+		},
+	}
+}
+
+// columnArgChanges converts the arguments that were given into file column values keyed by column name
+func columnArgChanges(args map[string]interface{}, columns []string) map[string]string {
+	changes := make(map[string]string, 0)
+	for _, column := range columns {
+		switch v := args[column].(type) {
+		case string:
+			changes[column] = v
+		case float64:
+			changes[column] = strconv.FormatFloat(v, 'f', -1, 64)
+		case int:
+			changes[column] = strconv.Itoa(v)
+		}
+	}
+
+	return changes
+}
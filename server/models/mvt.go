@@ -0,0 +1,205 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// mapbox vector tile geometry types
+const (
+	MVTPoint      = 1
+	MVTLineString = 2
+	MVTPolygon    = 3
+)
+
+// MVTExtent size of a tile in tile coordinates
+const MVTExtent = 4096
+
+// mvt geometry commands
+const (
+	mvtMoveTo    = 1
+	mvtLineTo    = 2
+	mvtClosePath = 7
+)
+
+// MVTFeature feature already projected into integer tile coordinates. Geometry holds one part of all points for
+// MVTPoint, one part per line string for MVTLineString and one part per ring for MVTPolygon, exterior rings first
+type MVTFeature struct {
+	ID         uint64
+	Type       int
+	Geometry   [][][2]int
+	Properties map[string]interface{}
+}
+
+// MVTLayer named layer of a vector tile
+type MVTLayer struct {
+	Name     string
+	Features []MVTFeature
+}
+
+// EncodeMVT encodes layers as a version 2.1 mapbox vector tile protobuf message
+func EncodeMVT(layers []MVTLayer) []byte {
+	tile := make([]byte, 0)
+	for _, l := range layers {
+		tile = appendBytesField(tile, 3, encodeMVTLayer(l))
+	}
+	return tile
+}
+
+func encodeMVTLayer(l MVTLayer) []byte {
+	layer := appendVarintField(nil, 15, 2)
+	layer = appendBytesField(layer, 1, []byte(l.Name))
+
+	keys := make([]string, 0)
+	keyIndex := make(map[string]int, 0)
+	values := make([][]byte, 0)
+	valueIndex := make(map[string]int, 0)
+	for _, f := range l.Features {
+		feature := make([]byte, 0)
+		if f.ID != 0 {
+			feature = appendVarintField(feature, 1, f.ID)
+		}
+
+		tags := make([]uint64, 0)
+		for _, key := range sortedKeys(f.Properties) {
+			value, ok := encodeMVTValue(f.Properties[key])
+			if !ok {
+				continue
+			}
+			k, found := keyIndex[key]
+			if !found {
+				k = len(keys)
+				keyIndex[key] = k
+				keys = append(keys, key)
+			}
+			v, found := valueIndex[string(value)]
+			if !found {
+				v = len(values)
+				valueIndex[string(value)] = v
+				values = append(values, value)
+			}
+			tags = append(tags, uint64(k), uint64(v))
+		}
+		if len(tags) > 0 {
+			feature = appendBytesField(feature, 2, packVarints(tags))
+		}
+		feature = appendVarintField(feature, 3, uint64(f.Type))
+		feature = appendBytesField(feature, 4, packVarints(encodeMVTGeometry(f.Type, f.Geometry)))
+
+		layer = appendBytesField(layer, 2, feature)
+	}
+	for _, key := range keys {
+		layer = appendBytesField(layer, 3, []byte(key))
+	}
+	for _, value := range values {
+		layer = appendBytesField(layer, 4, value)
+	}
+
+	return appendVarintField(layer, 5, MVTExtent)
+}
+
+// encodeMVTValue encodes a property as a tile Value message, integers as sint, other numbers as double,
+// and lists or objects as their json text. Null properties are left out
+func encodeMVTValue(v interface{}) ([]byte, bool) {
+	switch value := v.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return appendBytesField(nil, 1, []byte(value)), true
+	case bool:
+		b := uint64(0)
+		if value {
+			b = 1
+		}
+		return appendVarintField(nil, 7, b), true
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return appendVarintField(nil, 6, zigzag(int64(value))), true
+		}
+		key := appendVarint(nil, 3<<3|1)
+		bits := math.Float64bits(value)
+		for i := uint(0); i < 8; i++ {
+			key = append(key, byte(bits>>(8*i)))
+		}
+		return key, true
+	default:
+		text, err := json.Marshal(value)
+		if err != nil {
+			return nil, false
+		}
+		return appendBytesField(nil, 1, text), true
+	}
+}
+
+// encodeMVTGeometry turns parts into MoveTo, LineTo and ClosePath commands with zigzag encoded deltas
+func encodeMVTGeometry(geomType int, parts [][][2]int) []uint64 {
+	commands := make([]uint64, 0)
+	cursor := [2]int{0, 0}
+	delta := func(p [2]int) {
+		commands = append(commands, zigzag(int64(p[0]-cursor[0])), zigzag(int64(p[1]-cursor[1])))
+		cursor = p
+	}
+
+	if geomType == MVTPoint {
+		points := make([][2]int, 0)
+		for _, part := range parts {
+			points = append(points, part...)
+		}
+		if len(points) == 0 {
+			return commands
+		}
+		commands = append(commands, mvtCommand(mvtMoveTo, len(points)))
+		for _, p := range points {
+			delta(p)
+		}
+		return commands
+	}
+
+	for _, part := range parts {
+		commands = append(commands, mvtCommand(mvtMoveTo, 1))
+		delta(part[0])
+		commands = append(commands, mvtCommand(mvtLineTo, len(part)-1))
+		for _, p := range part[1:] {
+			delta(p)
+		}
+		if geomType == MVTPolygon {
+			commands = append(commands, mvtCommand(mvtClosePath, 1))
+		}
+	}
+	return commands
+}
+
+func mvtCommand(id int, count int) uint64 {
+	return uint64(id&0x7 | count<<3)
+}
+
+func zigzag(n int64) uint64 {
+	return uint64((n << 1) ^ (n >> 63))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field<<3))
+	return appendVarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendVarint(b, uint64(field<<3|2))
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func packVarints(vs []uint64) []byte {
+	packed := make([]byte, 0)
+	for _, v := range vs {
+		packed = appendVarint(packed, v)
+	}
+	return packed
+}
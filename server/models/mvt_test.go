@@ -0,0 +1,270 @@
+package models
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// pbField one field of a decoded protobuf message, with its varint value or its bytes
+type pbField struct {
+	number int
+	varint uint64
+	bytes  []byte
+}
+
+func readVarint(t *testing.T, b []byte) (uint64, []byte) {
+	v := uint64(0)
+	for shift := uint(0); len(b) > 0; shift = shift + 7 {
+		v = v | uint64(b[0]&0x7f)<<shift
+		if b[0] < 0x80 {
+			return v, b[1:]
+		}
+		b = b[1:]
+	}
+	t.Fatalf("varint runs past the end of the message")
+	return 0, nil
+}
+
+// decodeMessage splits a protobuf message into its varint, 64 bit and length delimited fields
+func decodeMessage(t *testing.T, b []byte) []pbField {
+	fields := make([]pbField, 0)
+	for len(b) > 0 {
+		var key uint64
+		key, b = readVarint(t, b)
+		f := pbField{number: int(key >> 3)}
+		switch key & 0x7 {
+		case 0:
+			f.varint, b = readVarint(t, b)
+		case 1:
+			f.bytes, b = b[:8], b[8:]
+		case 2:
+			var n uint64
+			n, b = readVarint(t, b)
+			f.bytes, b = b[:n], b[n:]
+		default:
+			t.Fatalf("field %d has unexpected wire type %d", f.number, key&0x7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func unpackVarints(t *testing.T, b []byte) []uint64 {
+	vs := make([]uint64, 0)
+	for len(b) > 0 {
+		var v uint64
+		v, b = readVarint(t, b)
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func TestZigzag(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want uint64
+	}{
+		{0, 0},
+		{-1, 1},
+		{1, 2},
+		{-2, 3},
+		{2, 4},
+		{4096, 8192},
+		{-4096, 8191},
+		{2147483647, 4294967294},
+		{-2147483648, 4294967295},
+	}
+
+	for _, tt := range tests {
+		if got := zigzag(tt.n); got != tt.want {
+			t.Errorf("zigzag(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAppendVarint(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{300, []byte{0xac, 0x02}},
+		{4096, []byte{0x80, 0x20}},
+		{1 << 32, []byte{0x80, 0x80, 0x80, 0x80, 0x10}},
+	}
+
+	for _, tt := range tests {
+		if got := appendVarint(nil, tt.v); !bytes.Equal(got, tt.want) {
+			t.Errorf("appendVarint(%d) = % x, want % x", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestMVTCommand(t *testing.T) {
+	// command integers from the examples of the vector tile spec
+	tests := []struct {
+		name  string
+		id    int
+		count int
+		want  uint64
+	}{
+		{"one MoveTo", mvtMoveTo, 1, 9},
+		{"two MoveTo", mvtMoveTo, 2, 17},
+		{"one LineTo", mvtLineTo, 1, 10},
+		{"three LineTo", mvtLineTo, 3, 26},
+		{"ClosePath", mvtClosePath, 1, 15},
+	}
+
+	for _, tt := range tests {
+		if got := mvtCommand(tt.id, tt.count); got != tt.want {
+			t.Errorf("%s: mvtCommand(%d, %d) = %d, want %d", tt.name, tt.id, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeMVTGeometry(t *testing.T) {
+	// geometries and encodings from the examples of the vector tile spec
+	tests := []struct {
+		name     string
+		geomType int
+		parts    [][][2]int
+		want     []uint64
+	}{
+		{"point", MVTPoint, [][][2]int{{{25, 17}}}, []uint64{9, 50, 34}},
+		{"multi point", MVTPoint, [][][2]int{{{5, 7}, {3, 2}}}, []uint64{17, 10, 14, 3, 9}},
+		{"line string", MVTLineString, [][][2]int{{{2, 2}, {2, 10}, {10, 10}}}, []uint64{9, 4, 4, 18, 0, 16, 16, 0}},
+		{"multi line string", MVTLineString, [][][2]int{{{2, 2}, {2, 10}, {10, 10}}, {{1, 1}, {3, 5}}},
+			[]uint64{9, 4, 4, 18, 0, 16, 16, 0, 9, 17, 17, 10, 4, 8}},
+		{"polygon", MVTPolygon, [][][2]int{{{3, 6}, {8, 12}, {20, 34}}}, []uint64{9, 6, 12, 18, 10, 12, 24, 44, 15}},
+		{"no points", MVTPoint, [][][2]int{}, []uint64{}},
+	}
+
+	for _, tt := range tests {
+		if got := encodeMVTGeometry(tt.geomType, tt.parts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: encodeMVTGeometry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeMVT(t *testing.T) {
+	tile := EncodeMVT([]MVTLayer{{
+		Name: "satellites",
+		Features: []MVTFeature{
+			{ID: 1, Type: MVTPoint, Geometry: [][][2]int{{{25, 17}}}, Properties: map[string]interface{}{
+				"id": "M001", "altitude": 1200.0, "speed": 7.25, "stale": false, "note": nil,
+			}},
+			{ID: 2, Type: MVTPoint, Geometry: [][][2]int{{{3, 2}}}, Properties: map[string]interface{}{
+				"id": "M002", "altitude": 1200.0,
+			}},
+		},
+	}})
+
+	layers := decodeMessage(t, tile)
+	if len(layers) != 1 || layers[0].number != 3 {
+		t.Fatalf("tile fields %+v, want one layer field 3", layers)
+	}
+	var name string
+	var version, extent uint64
+	features := make([][]pbField, 0)
+	keys := make([]string, 0)
+	values := make([][]pbField, 0)
+	for _, f := range decodeMessage(t, layers[0].bytes) {
+		switch f.number {
+		case 15:
+			version = f.varint
+		case 1:
+			name = string(f.bytes)
+		case 2:
+			features = append(features, decodeMessage(t, f.bytes))
+		case 3:
+			keys = append(keys, string(f.bytes))
+		case 4:
+			values = append(values, decodeMessage(t, f.bytes))
+		case 5:
+			extent = f.varint
+		}
+	}
+	if version != 2 || name != "satellites" || extent != MVTExtent {
+		t.Errorf("layer version %d, name %q, extent %d, want 2, satellites, %d", version, name, extent, MVTExtent)
+	}
+
+	// keys are written in sorted order and the null note is left out
+	if want := []string{"altitude", "id", "speed", "stale"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys %v, want %v", keys, want)
+	}
+	// the whole altitude is a sint, the fractional speed a double, and M002 shares the altitude value of M001
+	wantValues := []struct {
+		field int
+		value interface{}
+	}{
+		{6, uint64(2400)},
+		{1, "M001"},
+		{3, 7.25},
+		{7, uint64(0)},
+		{1, "M002"},
+	}
+	if len(values) != len(wantValues) {
+		t.Fatalf("got %d values, want %d", len(values), len(wantValues))
+	}
+	for i, want := range wantValues {
+		v := values[i][0]
+		var got interface{}
+		switch v.number {
+		case 1:
+			got = string(v.bytes)
+		case 3:
+			bits := uint64(0)
+			for j := range v.bytes {
+				bits = bits | uint64(v.bytes[j])<<(8*uint(j))
+			}
+			got = math.Float64frombits(bits)
+		default:
+			got = v.varint
+		}
+		if v.number != want.field || got != want.value {
+			t.Errorf("value %d is field %d %v, want field %d %v", i, v.number, got, want.field, want.value)
+		}
+	}
+
+	wantFeatures := []struct {
+		id       uint64
+		tags     []uint64
+		geometry []uint64
+	}{
+		{1, []uint64{0, 0, 1, 1, 2, 2, 3, 3}, []uint64{9, 50, 34}},
+		{2, []uint64{0, 0, 1, 4}, []uint64{9, 6, 4}},
+	}
+	if len(features) != len(wantFeatures) {
+		t.Fatalf("got %d features, want %d", len(features), len(wantFeatures))
+	}
+	for i, want := range wantFeatures {
+		var id, geomType uint64
+		var tags, geometry []uint64
+		for _, f := range features[i] {
+			switch f.number {
+			case 1:
+				id = f.varint
+			case 2:
+				tags = unpackVarints(t, f.bytes)
+			case 3:
+				geomType = f.varint
+			case 4:
+				geometry = unpackVarints(t, f.bytes)
+			}
+		}
+		if id != want.id || geomType != MVTPoint {
+			t.Errorf("feature %d id %d type %d, want %d and %d", i, id, geomType, want.id, MVTPoint)
+		}
+		if !reflect.DeepEqual(tags, want.tags) {
+			t.Errorf("feature %d tags %v, want %v", i, tags, want.tags)
+		}
+		if !reflect.DeepEqual(geometry, want.geometry) {
+			t.Errorf("feature %d geometry %v, want %v", i, geometry, want.geometry)
+		}
+	}
+}
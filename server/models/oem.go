@@ -0,0 +1,537 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+// oemDefaultDegrees interpolation degree used when an OEM segment does not give INTERPOLATION_DEGREE
+var oemDefaultDegrees = map[string]int{
+	"LAGRANGE": 7,
+	"HERMITE":  5,
+}
+
+// oemInertialFrames reference frames whose states are treated as inertial, J2000 frames are rotated to TEME of date
+var oemInertialFrames = []string{"TEME", "EME2000", "J2000", "GCRF", "ICRF"}
+
+// oemEarthFixedFrames reference frames whose states rotate with the earth
+var oemEarthFixedFrames = []string{"ITRF", "ITRF93", "ITRF97", "ITRF2000", "ITRF2005", "ITRF2008", "ITRF2014", "ITRF2020", "EFG", "ECEF"}
+
+// EphemerisPoint state vector of an OEM in kilometers and kilometers per second
+type EphemerisPoint struct {
+	Time     time.Time
+	Position satellite.Vector3
+	Velocity satellite.Vector3
+}
+
+// EphemerisSegment state vectors of an OEM segment sharing one frame and interpolation method
+type EphemerisSegment struct {
+	Frame         string
+	Interpolation string
+	Degree        int
+	Start         time.Time
+	Stop          time.Time
+	Points        []EphemerisPoint
+}
+
+// Ephemeris OEM segments of a single satellite
+type Ephemeris struct {
+	SatelliteID string
+	Source      string
+	Segments    []EphemerisSegment
+}
+
+// EphemerisStore OEM ephemerides loaded from the data directory keyed by satellite id
+type EphemerisStore struct {
+	mu  sync.RWMutex
+	eph map[string]*Ephemeris
+}
+
+// LoadedEphemerides ephemerides available to satellites whose roster source is oem
+var LoadedEphemerides = &EphemerisStore{
+	eph: make(map[string]*Ephemeris, 0),
+}
+
+// Get returns a satellite's ephemeris, or nil when none is loaded
+func (s *EphemerisStore) Get(id string) *Ephemeris {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.eph[id]
+}
+
+// Store replaces every loaded ephemeris
+func (s *EphemerisStore) Store(eph map[string]*Ephemeris) {
+	s.mu.Lock()
+	s.eph = eph
+	s.mu.Unlock()
+}
+
+// LoadOEMFiles reads every OEM file into the ephemeris store, skipping files that cannot be read
+func LoadOEMFiles(files []string, oemregex *regexp.Regexp) {
+	ephemerides := make(map[string]*Ephemeris, 0)
+	for _, file := range files {
+		if oemregex == nil || !oemregex.MatchString(filepath.Base(file)) {
+			continue
+		}
+		oemtext, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Println("Skipping OEM file", file, ":", err)
+			continue
+		}
+
+		fileEphemerides, err := ParseOEM(string(oemtext), filepath.Base(file))
+		if err != nil {
+			fmt.Println("Skipping OEM file", file, ":", err)
+			continue
+		}
+		for id, eph := range fileEphemerides {
+			if existing, ok := ephemerides[id]; ok {
+				existing.Segments = append(existing.Segments, eph.Segments...)
+				continue
+			}
+			ephemerides[id] = eph
+		}
+	}
+	LoadedEphemerides.Store(ephemerides)
+	fmt.Println("Loaded OEM ephemerides for", len(ephemerides), "satellites")
+}
+
+// oemSegment metadata keywords and raw state vectors of one OEM segment as read from the text
+type oemSegment struct {
+	meta   map[string]string
+	states [][]string
+}
+
+// ParseOEM reads CCSDS orbit ephemeris messages in KVN or XML form into ephemerides keyed by satellite id,
+// the last 4 characters of OBJECT_NAME as with tle names. Segments that cannot be used are skipped and logged
+func ParseOEM(text string, source string) (map[string]*Ephemeris, error) {
+	var segments []oemSegment
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(text), "<") {
+		segments, err = readOEMXML(text)
+	} else {
+		segments, err = readOEMKVN(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ephemerides := make(map[string]*Ephemeris, 0)
+	for i, s := range segments {
+		id := tleName(s.meta["OBJECT_NAME"])
+		segment, err := buildEphemerisSegment(s)
+		if err != nil {
+			fmt.Println("Skipping OEM segment", i+1, "of", source, "for", id, ":", err)
+			continue
+		}
+		if _, ok := ephemerides[id]; !ok {
+			ephemerides[id] = &Ephemeris{SatelliteID: id, Source: source}
+		}
+		ephemerides[id].Segments = append(ephemerides[id].Segments, segment)
+	}
+
+	return ephemerides, nil
+}
+
+// readOEMKVN splits keyword = value text into segments, each starting at a META_START line
+func readOEMKVN(text string) ([]oemSegment, error) {
+	segments := make([]oemSegment, 0)
+	inMeta, inCovariance := false, false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "COMMENT"):
+			continue
+		case line == "META_START":
+			segments = append(segments, oemSegment{meta: make(map[string]string, 0)})
+			inMeta = true
+			continue
+		case line == "META_STOP":
+			inMeta = false
+			continue
+		case line == "COVARIANCE_START":
+			inCovariance = true
+			continue
+		case line == "COVARIANCE_STOP":
+			inCovariance = false
+			continue
+		case inCovariance:
+			continue
+		}
+
+		if inMeta {
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("line %d is not a KEYWORD = value pair", n)
+			}
+			segments[len(segments)-1].meta[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			continue
+		}
+		// header keywords before the first segment
+		if strings.Contains(line, "=") {
+			continue
+		}
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("line %d has a state vector before META_START", n)
+		}
+		segments[len(segments)-1].states = append(segments[len(segments)-1].states, strings.Fields(line))
+	}
+
+	return segments, scanner.Err()
+}
+
+// readOEMXML collects the metadata and state vectors of every segment element
+func readOEMXML(text string) ([]oemSegment, error) {
+	segments := make([]oemSegment, 0)
+	decoder := xml.NewDecoder(strings.NewReader(text))
+
+	var current *oemSegment
+	var state map[string]string
+	var value bytes.Buffer
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "segment":
+				current = &oemSegment{meta: make(map[string]string, 0)}
+			case "stateVector":
+				state = make(map[string]string, 0)
+			}
+			value.Reset()
+		case xml.CharData:
+			value.Write(t)
+		case xml.EndElement:
+			if current == nil {
+				continue
+			}
+			switch t.Name.Local {
+			case "segment":
+				segments = append(segments, *current)
+				current = nil
+			case "stateVector":
+				current.states = append(current.states, []string{state["EPOCH"], state["X"], state["Y"], state["Z"], state["X_DOT"], state["Y_DOT"], state["Z_DOT"]})
+				state = nil
+			default:
+				v := strings.TrimSpace(value.String())
+				if state != nil {
+					state[t.Name.Local] = v
+				} else if v != "" {
+					current.meta[t.Name.Local] = v
+				}
+			}
+			value.Reset()
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no oem segments found")
+	}
+
+	return segments, nil
+}
+
+// buildEphemerisSegment checks the metadata of a segment and parses its state vectors
+func buildEphemerisSegment(s oemSegment) (EphemerisSegment, error) {
+	if center := s.meta["CENTER_NAME"]; center != "" && strings.ToUpper(center) != "EARTH" {
+		return EphemerisSegment{}, fmt.Errorf("center %s is not EARTH", center)
+	}
+	if timeSystem := s.meta["TIME_SYSTEM"]; timeSystem != "" && strings.ToUpper(timeSystem) != "UTC" {
+		return EphemerisSegment{}, fmt.Errorf("time system %s is not UTC", timeSystem)
+	}
+	frame := strings.ToUpper(s.meta["REF_FRAME"])
+	if !helpers.StringInSlice(frame, oemInertialFrames) && !helpers.StringInSlice(frame, oemEarthFixedFrames) {
+		return EphemerisSegment{}, fmt.Errorf("reference frame %q is not supported", s.meta["REF_FRAME"])
+	}
+
+	interpolation := strings.ToUpper(s.meta["INTERPOLATION"])
+	if interpolation == "" {
+		interpolation = "LAGRANGE"
+	}
+	degree, ok := oemDefaultDegrees[interpolation]
+	if !ok {
+		return EphemerisSegment{}, fmt.Errorf("interpolation %s is not LAGRANGE or HERMITE", interpolation)
+	}
+	if raw := s.meta["INTERPOLATION_DEGREE"]; raw != "" {
+		d, err := strconv.Atoi(raw)
+		if err != nil || d < 1 {
+			return EphemerisSegment{}, fmt.Errorf("interpolation degree %q is not a positive integer", raw)
+		}
+		degree = d
+	}
+
+	segment := EphemerisSegment{
+		Frame:         frame,
+		Interpolation: interpolation,
+		Degree:        degree,
+		Points:        make([]EphemerisPoint, 0),
+	}
+	for i, state := range s.states {
+		if len(state) < 7 {
+			return EphemerisSegment{}, fmt.Errorf("state vector %d has %d values, expected an epoch and 6 components", i+1, len(state))
+		}
+		t, err := parseCCSDSTime(state[0])
+		if err != nil {
+			return EphemerisSegment{}, fmt.Errorf("state vector %d: %v", i+1, err)
+		}
+		components := make([]float64, 6)
+		for j := range components {
+			components[j], err = strconv.ParseFloat(state[j+1], 64)
+			if err != nil {
+				return EphemerisSegment{}, fmt.Errorf("state vector %d component %q is not a number", i+1, state[j+1])
+			}
+		}
+		segment.Points = append(segment.Points, EphemerisPoint{
+			Time:     t,
+			Position: satellite.Vector3{X: components[0], Y: components[1], Z: components[2]},
+			Velocity: satellite.Vector3{X: components[3], Y: components[4], Z: components[5]},
+		})
+	}
+	if len(segment.Points) < 2 {
+		return EphemerisSegment{}, fmt.Errorf("segment needs at least 2 state vectors to interpolate")
+	}
+	sort.Slice(segment.Points, func(i, j int) bool {
+		return segment.Points[i].Time.Before(segment.Points[j].Time)
+	})
+
+	// interpolation is only trusted between the useable times, or the first and last state vectors
+	segment.Start = segment.Points[0].Time
+	segment.Stop = segment.Points[len(segment.Points)-1].Time
+	if t, err := parseCCSDSTime(s.meta["USEABLE_START_TIME"]); err == nil && t.After(segment.Start) {
+		segment.Start = t
+	}
+	if t, err := parseCCSDSTime(s.meta["USEABLE_STOP_TIME"]); err == nil && t.Before(segment.Stop) {
+		segment.Stop = t
+	}
+
+	return segment, nil
+}
+
+// Covers reports whether any segment of the ephemeris can be interpolated at t
+func (e *Ephemeris) Covers(t time.Time) bool {
+	_, ok := e.segmentAt(t)
+	return ok
+}
+
+func (e *Ephemeris) segmentAt(t time.Time) (EphemerisSegment, bool) {
+	for _, s := range e.Segments {
+		if !t.Before(s.Start) && !t.After(s.Stop) {
+			return s, true
+		}
+	}
+	return EphemerisSegment{}, false
+}
+
+// StateAt interpolates the ephemeris at t and returns the state in the same inertial frame and
+// greenwich sidereal time that PropagateSatellite returns for sgp4, or false when no segment covers t
+func (e *Ephemeris) StateAt(t time.Time) (satellite.Vector3, satellite.Vector3, float64, bool) {
+	segment, ok := e.segmentAt(t)
+	if !ok {
+		return satellite.Vector3{}, satellite.Vector3{}, 0, false
+	}
+
+	window := segment.window(t)
+	var pos, vel satellite.Vector3
+	if segment.Interpolation == "HERMITE" {
+		pos, vel = interpolateHermite(window, t)
+	} else {
+		pos, vel = interpolateLagrange(window, t)
+	}
+
+	utc := t.UTC()
+	y, m, d := utc.Date()
+	h, min, sec := utc.Clock()
+	gmst := satellite.GSTimeFromDate(y, int(m), d, h, min, sec)
+
+	switch {
+	case helpers.StringInSlice(segment.Frame, oemEarthFixedFrames):
+		pos, vel = rotateECEFToECI(pos, vel, gmst)
+	case segment.Frame != "TEME":
+		pos = rotateJ2000ToTEME(pos, utc)
+		vel = rotateJ2000ToTEME(vel, utc)
+	}
+
+	return pos, vel, gmst, true
+}
+
+// window picks the state vectors around t used by the segment's interpolation degree
+func (s EphemerisSegment) window(t time.Time) []EphemerisPoint {
+	n := s.Degree + 1
+	if s.Interpolation == "HERMITE" {
+		// hermite fits position and velocity, so n points give a polynomial of degree 2n-1
+		n = (s.Degree + 2) / 2
+	}
+	if n < 2 {
+		n = 2
+	}
+	if n > len(s.Points) {
+		n = len(s.Points)
+	}
+
+	next := sort.Search(len(s.Points), func(i int) bool {
+		return s.Points[i].Time.After(t)
+	})
+	first := next - n/2
+	if first < 0 {
+		first = 0
+	}
+	if first > len(s.Points)-n {
+		first = len(s.Points) - n
+	}
+
+	return s.Points[first : first+n]
+}
+
+// interpolateLagrange interpolates position and velocity components independently through the window
+func interpolateLagrange(points []EphemerisPoint, t time.Time) (satellite.Vector3, satellite.Vector3) {
+	x := t.Sub(points[0].Time).Seconds()
+	var pos, vel satellite.Vector3
+	for i, p := range points {
+		xi := p.Time.Sub(points[0].Time).Seconds()
+		weight := 1.0
+		for j, q := range points {
+			if i == j {
+				continue
+			}
+			xj := q.Time.Sub(points[0].Time).Seconds()
+			weight = weight * (x - xj) / (xi - xj)
+		}
+		pos = satellite.Vector3{X: pos.X + weight*p.Position.X, Y: pos.Y + weight*p.Position.Y, Z: pos.Z + weight*p.Position.Z}
+		vel = satellite.Vector3{X: vel.X + weight*p.Velocity.X, Y: vel.Y + weight*p.Velocity.Y, Z: vel.Z + weight*p.Velocity.Z}
+	}
+
+	return pos, vel
+}
+
+// interpolateHermite fits each position component and its velocity with a hermite polynomial through the window,
+// returning the polynomial and its derivative at t
+func interpolateHermite(points []EphemerisPoint, t time.Time) (satellite.Vector3, satellite.Vector3) {
+	x := t.Sub(points[0].Time).Seconds()
+	nodes := make([]float64, 0)
+	for _, p := range points {
+		xi := p.Time.Sub(points[0].Time).Seconds()
+		nodes = append(nodes, xi, xi)
+	}
+
+	component := func(value func(EphemerisPoint) float64, rate func(EphemerisPoint) float64) (float64, float64) {
+		// newton divided differences over the doubled nodes, using the rate where a node repeats
+		n := len(nodes)
+		coefficients := make([]float64, n)
+		for i := range nodes {
+			coefficients[i] = value(points[i/2])
+		}
+		for level := 1; level < n; level++ {
+			for i := n - 1; i >= level; i-- {
+				if level == 1 && i%2 == 1 {
+					coefficients[i] = rate(points[i/2])
+					continue
+				}
+				coefficients[i] = (coefficients[i] - coefficients[i-1]) / (nodes[i] - nodes[i-level])
+			}
+		}
+
+		f, df, product, dproduct := 0.0, 0.0, 1.0, 0.0
+		for i, c := range coefficients {
+			f = f + c*product
+			df = df + c*dproduct
+			dproduct = dproduct*(x-nodes[i]) + product
+			product = product * (x - nodes[i])
+		}
+		return f, df
+	}
+
+	px, vx := component(func(p EphemerisPoint) float64 { return p.Position.X }, func(p EphemerisPoint) float64 { return p.Velocity.X })
+	py, vy := component(func(p EphemerisPoint) float64 { return p.Position.Y }, func(p EphemerisPoint) float64 { return p.Velocity.Y })
+	pz, vz := component(func(p EphemerisPoint) float64 { return p.Position.Z }, func(p EphemerisPoint) float64 { return p.Velocity.Z })
+
+	return satellite.Vector3{X: px, Y: py, Z: pz}, satellite.Vector3{X: vx, Y: vy, Z: vz}
+}
+
+// rotateECEFToECI rotates an earth-fixed state into the inertial frame of greenwich sidereal time, adding the earth's rotation to the velocity
+func rotateECEFToECI(pos satellite.Vector3, vel satellite.Vector3, gmst float64) (satellite.Vector3, satellite.Vector3) {
+	inertialVel := satellite.Vector3{
+		X: vel.X - earthRotationRate*pos.Y,
+		Y: vel.Y + earthRotationRate*pos.X,
+		Z: vel.Z,
+	}
+
+	return RotateECIToECEF(pos, -gmst), RotateECIToECEF(inertialVel, -gmst)
+}
+
+// rotateJ2000ToTEME moves a J2000 vector into the TEME frame of date sgp4 works in: IAU 1976 precession to the mean
+// equinox of date, IAU 1980 nutation to the true equator of date, then the equation of the equinoxes back to the mean
+// equinox. Only the largest nutation terms are used, which keeps the result within about 1 arcsecond of TEME, and the
+// few milliarcsecond frame bias between J2000 and GCRF or ICRF is ignored
+func rotateJ2000ToTEME(v satellite.Vector3, t time.Time) satellite.Vector3 {
+	centuries := t.Sub(time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)).Hours() / 24 / 36525
+	arcsec := math.Pi / 180 / 3600
+	deg := math.Pi / 180
+
+	// mean longitudes of the sun and moon and of the moon's ascending node
+	sun := (280.4665 + 36000.7698*centuries) * deg
+	moon := (218.3165 + 481267.8813*centuries) * deg
+	node := (125.04452 - 1934.136261*centuries) * deg
+	dpsi := (-17.20*math.Sin(node) - 1.32*math.Sin(2*sun) - 0.23*math.Sin(2*moon) + 0.21*math.Sin(2*node)) * arcsec
+	deps := (9.20*math.Cos(node) + 0.57*math.Cos(2*sun) + 0.10*math.Cos(2*moon) - 0.09*math.Cos(2*node)) * arcsec
+	meanObliquity := (84381.448 - 46.8150*centuries - 0.00059*centuries*centuries + 0.001813*centuries*centuries*centuries) * arcsec
+	trueObliquity := meanObliquity + deps
+
+	v = precessJ2000ToDate(v, t)
+	v = rotateAboutX(v, meanObliquity)
+	v = rotateAboutZ(v, -dpsi)
+	v = rotateAboutX(v, -trueObliquity)
+	return rotateAboutZ(v, dpsi*math.Cos(trueObliquity))
+}
+
+// rotateAboutX rotates the axes of a vector's frame by angle radians about x
+func rotateAboutX(v satellite.Vector3, angle float64) satellite.Vector3 {
+	c, s := math.Cos(angle), math.Sin(angle)
+	return satellite.Vector3{X: v.X, Y: c*v.Y + s*v.Z, Z: -s*v.Y + c*v.Z}
+}
+
+// rotateAboutZ rotates the axes of a vector's frame by angle radians about z
+func rotateAboutZ(v satellite.Vector3, angle float64) satellite.Vector3 {
+	c, s := math.Cos(angle), math.Sin(angle)
+	return satellite.Vector3{X: c*v.X + s*v.Y, Y: -s*v.X + c*v.Y, Z: v.Z}
+}
+
+// precessJ2000ToDate applies IAU 1976 precession to move a J2000 vector to the mean equator and equinox of date
+func precessJ2000ToDate(v satellite.Vector3, t time.Time) satellite.Vector3 {
+	centuries := t.Sub(time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)).Hours() / 24 / 36525
+	arcsec := math.Pi / 180 / 3600
+	zeta := (2306.2181*centuries + 0.30188*centuries*centuries + 0.017998*centuries*centuries*centuries) * arcsec
+	z := (2306.2181*centuries + 1.09468*centuries*centuries + 0.018203*centuries*centuries*centuries) * arcsec
+	theta := (2004.3109*centuries - 0.42665*centuries*centuries - 0.041833*centuries*centuries*centuries) * arcsec
+
+	cz, sz := math.Cos(zeta), math.Sin(zeta)
+	cZ, sZ := math.Cos(z), math.Sin(z)
+	ct, st := math.Cos(theta), math.Sin(theta)
+
+	return satellite.Vector3{
+		X: (cZ*ct*cz-sZ*sz)*v.X + (-cZ*ct*sz-sZ*cz)*v.Y + (-cZ*st)*v.Z,
+		Y: (sZ*ct*cz+cZ*sz)*v.X + (-sZ*ct*sz+cZ*cz)*v.Y + (-sZ*st)*v.Z,
+		Z: (st*cz)*v.X + (-st*sz)*v.Y + ct*v.Z,
+	}
+}
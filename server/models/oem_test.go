@@ -0,0 +1,251 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+var oemTestEpoch = time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+// polynomialPoints samples a polynomial in seconds since oemTestEpoch on every component, with its derivative as velocity
+func polynomialPoints(coefficients []float64, step time.Duration, n int) []EphemerisPoint {
+	points := make([]EphemerisPoint, 0)
+	for i := 0; i < n; i++ {
+		t := oemTestEpoch.Add(time.Duration(i) * step)
+		f, df := evaluatePolynomial(coefficients, t.Sub(oemTestEpoch).Seconds())
+		points = append(points, EphemerisPoint{
+			Time:     t,
+			Position: satellite.Vector3{X: f, Y: 2 * f, Z: -f},
+			Velocity: satellite.Vector3{X: df, Y: 2 * df, Z: -df},
+		})
+	}
+	return points
+}
+
+func evaluatePolynomial(coefficients []float64, x float64) (float64, float64) {
+	f, df := 0.0, 0.0
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		df = df*x + f
+		f = f*x + coefficients[i]
+	}
+	return f, df
+}
+
+func closeVector(a satellite.Vector3, b satellite.Vector3, tolerance float64) bool {
+	return math.Abs(a.X-b.X) <= tolerance && math.Abs(a.Y-b.Y) <= tolerance && math.Abs(a.Z-b.Z) <= tolerance
+}
+
+func TestInterpolationReproducesNodes(t *testing.T) {
+	points := []EphemerisPoint{
+		{oemTestEpoch, satellite.Vector3{X: 7000, Y: 0, Z: 0}, satellite.Vector3{X: 0, Y: 7.5, Z: 0}},
+		{oemTestEpoch.Add(60 * time.Second), satellite.Vector3{X: 6985, Y: 449, Z: 12}, satellite.Vector3{X: -0.5, Y: 7.48, Z: 0.4}},
+		{oemTestEpoch.Add(120 * time.Second), satellite.Vector3{X: 6939, Y: 897, Z: 25}, satellite.Vector3{X: -1.0, Y: 7.43, Z: 0.41}},
+		{oemTestEpoch.Add(180 * time.Second), satellite.Vector3{X: 6863, Y: 1340, Z: 37}, satellite.Vector3{X: -1.5, Y: 7.35, Z: 0.42}},
+	}
+	interpolations := []struct {
+		name        string
+		interpolate func([]EphemerisPoint, time.Time) (satellite.Vector3, satellite.Vector3)
+	}{
+		{"lagrange", interpolateLagrange},
+		{"hermite", interpolateHermite},
+	}
+
+	for _, interpolation := range interpolations {
+		for i, p := range points {
+			pos, vel := interpolation.interpolate(points, p.Time)
+			if !closeVector(pos, p.Position, 1e-6) || !closeVector(vel, p.Velocity, 1e-6) {
+				t.Errorf("%s at node %d = %v %v, want %v %v", interpolation.name, i, pos, vel, p.Position, p.Velocity)
+			}
+		}
+	}
+}
+
+func TestHermiteMatchesPolynomial(t *testing.T) {
+	tests := []struct {
+		name         string
+		coefficients []float64
+		nodes        int
+	}{
+		{"cubic through 2 nodes", []float64{7000, 7.5, -0.004, 2e-6}, 2},
+		{"quintic through 3 nodes", []float64{6500, -3.2, 0.001, 4e-6, -2e-8, 3e-11}, 3},
+		{"degree 7 through 4 nodes", []float64{100, 1, 0.01, 1e-4, 1e-6, -1e-8, 1e-10, -1e-12}, 4},
+	}
+
+	for _, tt := range tests {
+		points := polynomialPoints(tt.coefficients, 60*time.Second, tt.nodes)
+		for _, seconds := range []float64{0, 17, 45.5, 60, 89, 119.25} {
+			if seconds > float64(60*(tt.nodes-1)) {
+				continue
+			}
+			f, df := evaluatePolynomial(tt.coefficients, seconds)
+			at := oemTestEpoch.Add(time.Duration(seconds * float64(time.Second)))
+			pos, vel := interpolateHermite(points, at)
+			if !closeVector(pos, satellite.Vector3{X: f, Y: 2 * f, Z: -f}, 1e-6) || !closeVector(vel, satellite.Vector3{X: df, Y: 2 * df, Z: -df}, 1e-8) {
+				t.Errorf("%s at %gs = %v %v, want %g %g", tt.name, seconds, pos, vel, f, df)
+			}
+		}
+	}
+}
+
+const oemTestKVN = `CCSDS_OEM_VERS = 2.0
+CREATION_DATE = 2020-03-01T00:00:00
+ORIGINATOR = TEST
+
+META_START
+OBJECT_NAME = TEST-M001
+OBJECT_ID = 2020-001A
+CENTER_NAME = EARTH
+REF_FRAME = TEME
+TIME_SYSTEM = UTC
+START_TIME = 2020-03-01T00:00:00.000
+USEABLE_START_TIME = 2020-03-01T00:01:00.000
+USEABLE_STOP_TIME = 2020-03-01T00:03:00.000
+STOP_TIME = 2020-03-01T00:04:00.000
+INTERPOLATION = HERMITE
+INTERPOLATION_DEGREE = 3
+META_STOP
+
+COMMENT states in km and km/s
+2020-03-01T00:00:00.000 7000.0 0.0 0.0 0.0 7.5 0.0
+2020-03-01T00:01:00.000 6985.0 449.0 12.0 -0.5 7.48 0.4
+2020-03-01T00:02:00.000 6939.0 897.0 25.0 -1.0 7.43 0.41
+2020-03-01T00:03:00.000 6863.0 1340.0 37.0 -1.5 7.35 0.42
+2020-03-01T00:04:00.000 6757.0 1775.0 49.0 -2.0 7.25 0.43
+
+META_START
+OBJECT_NAME = TEST-M001
+CENTER_NAME = EARTH
+REF_FRAME = ITRF2014
+TIME_SYSTEM = UTC
+START_TIME = 2020-03-01T00:10:00.000
+STOP_TIME = 2020-03-01T00:11:00.000
+META_STOP
+
+2020-03-01T00:10:00.000 7000.0 0.0 0.0 0.0 7.5 0.0
+2020-03-01T00:11:00.000 6985.0 449.0 12.0 -0.5 7.48 0.4
+`
+
+const oemTestXML = `<?xml version="1.0" encoding="UTF-8"?>
+<oem id="CCSDS_OEM_VERS" version="2.0">
+  <header>
+    <CREATION_DATE>2020-03-01T00:00:00</CREATION_DATE>
+    <ORIGINATOR>TEST</ORIGINATOR>
+  </header>
+  <body>
+    <segment>
+      <metadata>
+        <OBJECT_NAME>TEST-M001</OBJECT_NAME>
+        <OBJECT_ID>2020-001A</OBJECT_ID>
+        <CENTER_NAME>EARTH</CENTER_NAME>
+        <REF_FRAME>TEME</REF_FRAME>
+        <TIME_SYSTEM>UTC</TIME_SYSTEM>
+        <START_TIME>2020-03-01T00:00:00.000</START_TIME>
+        <USEABLE_START_TIME>2020-03-01T00:01:00.000</USEABLE_START_TIME>
+        <USEABLE_STOP_TIME>2020-03-01T00:03:00.000</USEABLE_STOP_TIME>
+        <STOP_TIME>2020-03-01T00:04:00.000</STOP_TIME>
+        <INTERPOLATION>HERMITE</INTERPOLATION>
+        <INTERPOLATION_DEGREE>3</INTERPOLATION_DEGREE>
+      </metadata>
+      <data>
+        <COMMENT>states in km and km/s</COMMENT>
+        <stateVector><EPOCH>2020-03-01T00:00:00.000</EPOCH><X>7000.0</X><Y>0.0</Y><Z>0.0</Z><X_DOT>0.0</X_DOT><Y_DOT>7.5</Y_DOT><Z_DOT>0.0</Z_DOT></stateVector>
+        <stateVector><EPOCH>2020-03-01T00:01:00.000</EPOCH><X>6985.0</X><Y>449.0</Y><Z>12.0</Z><X_DOT>-0.5</X_DOT><Y_DOT>7.48</Y_DOT><Z_DOT>0.4</Z_DOT></stateVector>
+        <stateVector><EPOCH>2020-03-01T00:02:00.000</EPOCH><X>6939.0</X><Y>897.0</Y><Z>25.0</Z><X_DOT>-1.0</X_DOT><Y_DOT>7.43</Y_DOT><Z_DOT>0.41</Z_DOT></stateVector>
+        <stateVector><EPOCH>2020-03-01T00:03:00.000</EPOCH><X>6863.0</X><Y>1340.0</Y><Z>37.0</Z><X_DOT>-1.5</X_DOT><Y_DOT>7.35</Y_DOT><Z_DOT>0.42</Z_DOT></stateVector>
+        <stateVector><EPOCH>2020-03-01T00:04:00.000</EPOCH><X>6757.0</X><Y>1775.0</Y><Z>49.0</Z><X_DOT>-2.0</X_DOT><Y_DOT>7.25</Y_DOT><Z_DOT>0.43</Z_DOT></stateVector>
+      </data>
+    </segment>
+    <segment>
+      <metadata>
+        <OBJECT_NAME>TEST-M001</OBJECT_NAME>
+        <CENTER_NAME>EARTH</CENTER_NAME>
+        <REF_FRAME>ITRF2014</REF_FRAME>
+        <TIME_SYSTEM>UTC</TIME_SYSTEM>
+        <START_TIME>2020-03-01T00:10:00.000</START_TIME>
+        <STOP_TIME>2020-03-01T00:11:00.000</STOP_TIME>
+      </metadata>
+      <data>
+        <stateVector><EPOCH>2020-03-01T00:10:00.000</EPOCH><X>7000.0</X><Y>0.0</Y><Z>0.0</Z><X_DOT>0.0</X_DOT><Y_DOT>7.5</Y_DOT><Z_DOT>0.0</Z_DOT></stateVector>
+        <stateVector><EPOCH>2020-03-01T00:11:00.000</EPOCH><X>6985.0</X><Y>449.0</Y><Z>12.0</Z><X_DOT>-0.5</X_DOT><Y_DOT>7.48</Y_DOT><Z_DOT>0.4</Z_DOT></stateVector>
+      </data>
+    </segment>
+  </body>
+</oem>
+`
+
+func TestParseOEMKVNMatchesXML(t *testing.T) {
+	kvn, err := ParseOEM(oemTestKVN, "test")
+	if err != nil {
+		t.Fatalf("ParseOEM kvn: %v", err)
+	}
+	xml, err := ParseOEM(oemTestXML, "test")
+	if err != nil {
+		t.Fatalf("ParseOEM xml: %v", err)
+	}
+
+	if !reflect.DeepEqual(kvn, xml) {
+		t.Errorf("kvn and xml ephemerides differ:\n%+v\n%+v", kvn["M001"], xml["M001"])
+	}
+	eph, ok := kvn["M001"]
+	if !ok {
+		t.Fatalf("ParseOEM ids = %v, want M001", reflect.ValueOf(kvn).MapKeys())
+	}
+	if len(eph.Segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(eph.Segments))
+	}
+	first := eph.Segments[0]
+	if first.Frame != "TEME" || first.Interpolation != "HERMITE" || first.Degree != 3 || len(first.Points) != 5 {
+		t.Errorf("first segment = %s %s degree %d with %d points, want TEME HERMITE degree 3 with 5 points", first.Frame, first.Interpolation, first.Degree, len(first.Points))
+	}
+	second := eph.Segments[1]
+	if second.Frame != "ITRF2014" || second.Interpolation != "LAGRANGE" || second.Degree != oemDefaultDegrees["LAGRANGE"] {
+		t.Errorf("second segment = %s %s degree %d, want ITRF2014 LAGRANGE degree %d", second.Frame, second.Interpolation, second.Degree, oemDefaultDegrees["LAGRANGE"])
+	}
+}
+
+func TestStateAtSegmentEdges(t *testing.T) {
+	ephemerides, err := ParseOEM(oemTestKVN, "test")
+	if err != nil {
+		t.Fatalf("ParseOEM: %v", err)
+	}
+	eph := ephemerides["M001"]
+
+	tests := []struct {
+		name    string
+		offset  time.Duration
+		covered bool
+	}{
+		{"first state before useable start", 0, false},
+		{"just before useable start", 59 * time.Second, false},
+		{"useable start", 1 * time.Minute, true},
+		{"inside first segment", 150 * time.Second, true},
+		{"useable stop", 3 * time.Minute, true},
+		{"just after useable stop", 181 * time.Second, false},
+		{"last state after useable stop", 4 * time.Minute, false},
+		{"gap between segments", 7 * time.Minute, false},
+		{"second segment start", 10 * time.Minute, true},
+		{"second segment stop", 11 * time.Minute, true},
+		{"after last segment", 11*time.Minute + time.Second, false},
+	}
+
+	for _, tt := range tests {
+		at := oemTestEpoch.Add(tt.offset)
+		_, _, _, ok := eph.StateAt(at)
+		if ok != tt.covered || eph.Covers(at) != tt.covered {
+			t.Errorf("%s: StateAt covered = %v, want %v", tt.name, ok, tt.covered)
+		}
+	}
+
+	// the useable start and stop of a teme segment fall on nodes, so the states come back unchanged
+	for _, node := range []int{1, 3} {
+		p := eph.Segments[0].Points[node]
+		pos, vel, _, _ := eph.StateAt(p.Time)
+		if !closeVector(pos, p.Position, 1e-6) || !closeVector(vel, p.Velocity, 1e-6) {
+			t.Errorf("StateAt node %d = %v %v, want %v %v", node, pos, vel, p.Position, p.Velocity)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+// alpha5Letters first characters of Alpha-5 catalog numbers for 100000 to 339999, I and O are skipped
+const alpha5Letters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// ccsdsTimeLayouts CCSDS time formats accepted for OMM and OEM epochs
+var ccsdsTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-002T15:04:05.999999999Z07:00",
+	"2006-002T15:04:05.999999999",
+}
+
+// ommMessage keywords of a single orbit mean-elements message and the line of the text it starts on
+type ommMessage struct {
+	line   int
+	fields map[string]string
+}
+
+// GetOMMTLES reads a CCSDS OMM file in XML or KVN form into a map of tle lines keyed by satellite id
+func GetOMMTLES(omm string) (map[string]map[string]string, error) {
+	ommtext, err := ioutil.ReadFile(omm)
+	if err != nil {
+		return nil, err
+	}
+
+	tlemap, report := ParseOMM(string(ommtext), filepath.Base(omm))
+	RecordIngestReport(report)
+	if len(report.Issues) > 0 {
+		fmt.Println("OMM file", omm, "has", len(report.Issues), "issues, rejected satellites:", report.Rejected)
+	}
+
+	return tlemap, nil
+}
+
+// IsOMM reports whether element text is a CCSDS OMM rather than a 3 line element set
+func IsOMM(text string) bool {
+	trimmed := strings.TrimSpace(text)
+	return strings.HasPrefix(trimmed, "<") || strings.HasPrefix(trimmed, "CCSDS_OMM_VERS")
+}
+
+// ParseElements reads element text as either a CCSDS OMM or a 3 line element set
+func ParseElements(text string, source string) (map[string]map[string]string, IngestReport) {
+	if IsOMM(text) {
+		return ParseOMM(text, source)
+	}
+
+	return ParseTLESet(text, source)
+}
+
+// ParseOMM reads CCSDS orbit mean-elements messages in XML or KVN form and converts each into tle lines,
+// so OMM satellites flow through the same validation, FLEET records and sgp4 initialization as tles
+func ParseOMM(text string, source string) (map[string]map[string]string, IngestReport) {
+	report := IngestReport{
+		Source:   source,
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		Accepted: make([]string, 0),
+		Rejected: make([]string, 0),
+		Issues:   make([]TLEIssue, 0),
+	}
+
+	var messages []ommMessage
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(text), "<") {
+		messages, err = readOMMXML(text)
+	} else {
+		messages, err = readOMMKVN(text)
+	}
+	if err != nil {
+		report.Issues = append(report.Issues, TLEIssue{Field: "format", Severity: "error", Message: err.Error()})
+		return map[string]map[string]string{}, report
+	}
+
+	tlemap := make(map[string]map[string]string, 0)
+	for _, m := range messages {
+		satID := tleName(m.fields["OBJECT_NAME"])
+		if satID == "" {
+			satID = m.fields["NORAD_CAT_ID"]
+		}
+
+		line1, line2, err := OMMToTLE(m.fields)
+		if err != nil {
+			report.Issues = append(report.Issues, TLEIssue{Line: m.line, SatelliteID: satID, Field: "omm", Severity: "error", Message: err.Error()})
+			report.Rejected = append(report.Rejected, satID)
+			continue
+		}
+		issues := ValidateTLE(satID, line1, line2, m.line)
+		if len(issues) > 0 {
+			report.Issues = append(report.Issues, issues...)
+			report.Rejected = append(report.Rejected, satID)
+			continue
+		}
+
+		if _, ok := tlemap[satID]; ok {
+			report.Issues = append(report.Issues, TLEIssue{Line: m.line, SatelliteID: satID, Field: "name", Severity: "warning", Message: fmt.Sprintf("satellite %s appears more than once, keeping the last message", satID)})
+		} else {
+			report.Accepted = append(report.Accepted, satID)
+		}
+		tlemap[satID] = map[string]string{
+			"firstline":  line1,
+			"secondline": line2,
+		}
+	}
+
+	return tlemap, report
+}
+
+// readOMMKVN splits keyword = value text into messages, each starting at a CCSDS_OMM_VERS line
+func readOMMKVN(text string) ([]ommMessage, error) {
+	messages := make([]ommMessage, 0)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		// block markers such as META_START and DATA_STOP carry no keywords
+		if line == "" || strings.HasPrefix(line, "COMMENT") || strings.HasSuffix(line, "_START") || strings.HasSuffix(line, "_STOP") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d is not a KEYWORD = value pair", n)
+		}
+		key := strings.TrimSpace(parts[0])
+		// values may carry units in brackets, such as 15.72125391 [rev/day]
+		value := strings.TrimSpace(strings.SplitN(parts[1], "[", 2)[0])
+
+		if key == "CCSDS_OMM_VERS" {
+			messages = append(messages, ommMessage{line: n, fields: make(map[string]string, 0)})
+		}
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("line %d comes before CCSDS_OMM_VERS", n)
+		}
+		messages[len(messages)-1].fields[key] = value
+	}
+
+	return messages, scanner.Err()
+}
+
+// readOMMXML collects the leaf elements of every omm element, which may be standalone or wrapped in an ndm
+func readOMMXML(text string) ([]ommMessage, error) {
+	messages := make([]ommMessage, 0)
+	decoder := xml.NewDecoder(strings.NewReader(text))
+
+	var current *ommMessage
+	var value bytes.Buffer
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if strings.EqualFold(t.Name.Local, "omm") {
+				line := strings.Count(text[:offset], "\n") + 1
+				current = &ommMessage{line: line, fields: make(map[string]string, 0)}
+			}
+			value.Reset()
+		case xml.CharData:
+			value.Write(t)
+		case xml.EndElement:
+			if current == nil {
+				continue
+			}
+			if strings.EqualFold(t.Name.Local, "omm") {
+				messages = append(messages, *current)
+				current = nil
+				continue
+			}
+			if v := strings.TrimSpace(value.String()); v != "" {
+				current.fields[t.Name.Local] = v
+			}
+			value.Reset()
+		}
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no omm elements found")
+	}
+
+	return messages, nil
+}
+
+// OMMToTLE formats the mean elements and tle parameters of an OMM as the two lines of a tle
+func OMMToTLE(fields map[string]string) (string, string, error) {
+	if theory := fields["MEAN_ELEMENT_THEORY"]; theory != "" && !strings.Contains(strings.ToUpper(theory), "SGP4") {
+		return "", "", fmt.Errorf("mean element theory %s is not SGP4", theory)
+	}
+
+	number := func(key string, required bool) (float64, error) {
+		raw, ok := fields[key]
+		if !ok || raw == "" {
+			if required {
+				return 0, fmt.Errorf("%s is required", key)
+			}
+			return 0, nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s %q is not a number", key, raw)
+		}
+		return v, nil
+	}
+	values := make(map[string]float64, 0)
+	for _, key := range []string{"MEAN_MOTION", "ECCENTRICITY", "INCLINATION", "RA_OF_ASC_NODE", "ARG_OF_PERICENTER", "MEAN_ANOMALY", "NORAD_CAT_ID"} {
+		v, err := number(key, true)
+		if err != nil {
+			return "", "", err
+		}
+		values[key] = v
+	}
+	for _, key := range []string{"BSTAR", "MEAN_MOTION_DOT", "MEAN_MOTION_DDOT", "ELEMENT_SET_NO", "REV_AT_EPOCH", "EPHEMERIS_TYPE"} {
+		v, err := number(key, false)
+		if err != nil {
+			return "", "", err
+		}
+		values[key] = v
+	}
+
+	catalog, err := Alpha5(int(values["NORAD_CAT_ID"]))
+	if err != nil {
+		return "", "", err
+	}
+	epoch, err := parseCCSDSTime(fields["EPOCH"])
+	if err != nil {
+		return "", "", fmt.Errorf("EPOCH %v", err)
+	}
+	classification := fields["CLASSIFICATION_TYPE"]
+	if classification == "" {
+		classification = "U"
+	}
+	meanMotionDot, err := tleDecimal(values["MEAN_MOTION_DOT"])
+	if err != nil {
+		return "", "", fmt.Errorf("MEAN_MOTION_DOT %v", err)
+	}
+	meanMotionDDot, err := tleExponent(values["MEAN_MOTION_DDOT"])
+	if err != nil {
+		return "", "", fmt.Errorf("MEAN_MOTION_DDOT %v", err)
+	}
+	bstar, err := tleExponent(values["BSTAR"])
+	if err != nil {
+		return "", "", fmt.Errorf("BSTAR %v", err)
+	}
+
+	line1 := fmt.Sprintf("1 %5s%1.1s %-8.8s %s %s %s %s %1d %4d",
+		catalog,
+		classification,
+		tleDesignator(fields["OBJECT_ID"]),
+		tleEpochField(epoch),
+		meanMotionDot,
+		meanMotionDDot,
+		bstar,
+		int(values["EPHEMERIS_TYPE"])%10,
+		int(values["ELEMENT_SET_NO"])%10000,
+	)
+	line2 := fmt.Sprintf("2 %5s %8.4f %8.4f %07d %8.4f %8.4f %11.8f%5d",
+		catalog,
+		values["INCLINATION"],
+		values["RA_OF_ASC_NODE"],
+		int(math.Round(values["ECCENTRICITY"]*1e7)),
+		values["ARG_OF_PERICENTER"],
+		values["MEAN_ANOMALY"],
+		values["MEAN_MOTION"],
+		int(values["REV_AT_EPOCH"])%100000,
+	)
+	line1 = line1 + strconv.Itoa(TLEChecksum(line1))
+	line2 = line2 + strconv.Itoa(TLEChecksum(line2))
+
+	return line1, line2, nil
+}
+
+// Alpha5 formats a catalog number in the 5 columns of a tle, using a leading letter for 100000 to 339999
+func Alpha5(n int) (string, error) {
+	switch {
+	case n < 0:
+		return "", fmt.Errorf("catalog number %d is negative", n)
+	case n < 100000:
+		return fmt.Sprintf("%05d", n), nil
+	case n < 340000:
+		return fmt.Sprintf("%c%04d", alpha5Letters[n/10000-10], n%10000), nil
+	default:
+		return "", fmt.Errorf("catalog number %d does not fit in Alpha-5", n)
+	}
+}
+
+// ParseAlpha5 reads a tle catalog number that may start with an Alpha-5 letter
+func ParseAlpha5(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("catalog number is empty")
+	}
+	if i := strings.IndexByte(alpha5Letters, s[0]); i >= 0 {
+		n, err := strconv.Atoi(s[1:])
+		if err != nil || len(s) != 5 {
+			return 0, fmt.Errorf("catalog number %q is not Alpha-5", s)
+		}
+		return (i+10)*10000 + n, nil
+	}
+
+	return strconv.Atoi(s)
+}
+
+// parseCCSDSTime parses a CCSDS calendar or day of year time, taken as utc when it has no zone
+func parseCCSDSTime(s string) (time.Time, error) {
+	for _, layout := range ccsdsTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a CCSDS time", s)
+}
+
+// tleDesignator converts an international designator such as 1998-067A into the tle form 98067A
+func tleDesignator(objectID string) string {
+	if len(objectID) >= 9 && objectID[4] == '-' {
+		return objectID[2:4] + objectID[5:]
+	}
+
+	return objectID
+}
+
+// tleEpochField formats an epoch as the two digit year and fractional day of year of a tle
+func tleEpochField(t time.Time) string {
+	start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	day := 1 + t.Sub(start).Hours()/24
+
+	return fmt.Sprintf("%02d%012.8f", t.Year()%100, day)
+}
+
+// tleDecimal formats a value below 1 as a sign and a decimal without its leading zero, such as -.00002182
+func tleDecimal(v float64) (string, error) {
+	if math.Abs(v) >= 1 {
+		return "", fmt.Errorf("%v does not fit in a tle decimal field", v)
+	}
+	sign := " "
+	if v < 0 {
+		sign = "-"
+	}
+
+	return sign + strings.TrimPrefix(fmt.Sprintf("%.8f", math.Abs(v)), "0"), nil
+}
+
+// tleExponent formats a value in the assumed decimal point notation of a tle, such as -11606-4 for -0.11606e-4
+func tleExponent(v float64) (string, error) {
+	if v == 0 {
+		return " 00000-0", nil
+	}
+	sign := " "
+	if v < 0 {
+		sign = "-"
+	}
+	exponent := int(math.Floor(math.Log10(math.Abs(v)))) + 1
+	mantissa := int(math.Round(math.Abs(v) / math.Pow(10, float64(exponent)) * 1e5))
+	if mantissa >= 100000 {
+		mantissa = mantissa / 10
+		exponent++
+	}
+	if exponent < -9 {
+		return " 00000-0", nil
+	}
+	if exponent > 9 {
+		return "", fmt.Errorf("%v does not fit in a tle exponent field", v)
+	}
+	exponentSign := "+"
+	if exponent < 0 {
+		exponentSign = "-"
+	}
+
+	return fmt.Sprintf("%s%05d%s%d", sign, mantissa, exponentSign, int(math.Abs(float64(exponent)))), nil
+}
+
+// TLEToSGP4 initializes sgp4 from tle lines. go-satellite reads the catalog number as an integer and exits
+// on Alpha-5 letters, so the catalog columns are zeroed first; they play no part in propagation
+func TLEToSGP4(line1 string, line2 string) satellite.Satellite {
+	if len(line1) >= 7 && len(line2) >= 7 {
+		line1 = line1[:2] + "00000" + line1[7:]
+		line2 = line2[:2] + "00000" + line2[7:]
+	}
+
+	return satellite.TLEToSat(line1, line2, "wgs84")
+}
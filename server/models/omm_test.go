@@ -0,0 +1,162 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+)
+
+// issOMM mean elements of the ISS tle in issLine1 and issLine2
+func issOMM() map[string]string {
+	return map[string]string{
+		"OBJECT_NAME":         "ISS (ZARYA)",
+		"OBJECT_ID":           "1998-067A",
+		"EPOCH":               "2008-09-20T12:25:40.104192",
+		"MEAN_MOTION":         "15.72125391",
+		"ECCENTRICITY":        "0.0006703",
+		"INCLINATION":         "51.6416",
+		"RA_OF_ASC_NODE":      "247.4627",
+		"ARG_OF_PERICENTER":   "130.5360",
+		"MEAN_ANOMALY":        "325.0288",
+		"EPHEMERIS_TYPE":      "0",
+		"CLASSIFICATION_TYPE": "U",
+		"NORAD_CAT_ID":        "25544",
+		"ELEMENT_SET_NO":      "292",
+		"REV_AT_EPOCH":        "56353",
+		"BSTAR":               "-0.11606E-4",
+		"MEAN_MOTION_DOT":     "-0.00002182",
+		"MEAN_MOTION_DDOT":    "0",
+	}
+}
+
+func TestOMMToTLE(t *testing.T) {
+	line1, line2, err := OMMToTLE(issOMM())
+	if err != nil {
+		t.Fatalf("OMMToTLE: %v", err)
+	}
+	if line1 != issLine1 {
+		t.Errorf("line 1 =\n%q, want\n%q", line1, issLine1)
+	}
+	if line2 != issLine2 {
+		t.Errorf("line 2 =\n%q, want\n%q", line2, issLine2)
+	}
+	if issues := ValidateTLE("M001", line1, line2, 1); len(issues) != 0 {
+		t.Errorf("ValidateTLE of the converted tle = %+v, want no issues", issues)
+	}
+}
+
+func TestOMMToTLEColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		line   int
+		start  int
+		end    int
+		want   string
+	}{
+		{"alpha-5 catalog number", map[string]string{"NORAD_CAT_ID": "270001"}, 1, 2, 7, "T0001"},
+		{"small catalog number is zero padded", map[string]string{"NORAD_CAT_ID": "5"}, 2, 2, 7, "00005"},
+		{"positive mean motion dot keeps its sign column", map[string]string{"MEAN_MOTION_DOT": "0.00012345"}, 1, 33, 43, " .00012345"},
+		{"small bstar", map[string]string{"BSTAR": "0.00001"}, 1, 53, 61, " 10000-4"},
+		{"negative mean motion ddot", map[string]string{"MEAN_MOTION_DDOT": "-1.2345E-6"}, 1, 44, 52, "-12345-5"},
+		{"element set number wraps at 4 digits", map[string]string{"ELEMENT_SET_NO": "12345"}, 1, 64, 68, "2345"},
+		{"revolution number wraps at 5 digits", map[string]string{"REV_AT_EPOCH": "123456"}, 2, 63, 68, "23456"},
+		{"single digit inclination", map[string]string{"INCLINATION": "7.5"}, 2, 8, 16, "  7.5000"},
+		{"day of year epoch", map[string]string{"EPOCH": "2021-001T00:00:00"}, 1, 18, 32, "21001.00000000"},
+	}
+
+	for _, tt := range tests {
+		fields := issOMM()
+		for k, v := range tt.fields {
+			fields[k] = v
+		}
+		line1, line2, err := OMMToTLE(fields)
+		if err != nil {
+			t.Errorf("%s: OMMToTLE: %v", tt.name, err)
+			continue
+		}
+		for i, line := range []string{line1, line2} {
+			if len(line) != tleLineLength {
+				t.Errorf("%s: line %d has %d characters, want %d", tt.name, i+1, len(line), tleLineLength)
+				continue
+			}
+			if checksum, _ := strconv.Atoi(line[68:]); checksum != TLEChecksum(line) {
+				t.Errorf("%s: line %d checksum %d, want %d", tt.name, i+1, checksum, TLEChecksum(line))
+			}
+		}
+		line := line1
+		if tt.line == 2 {
+			line = line2
+		}
+		if len(line) == tleLineLength && line[tt.start:tt.end] != tt.want {
+			t.Errorf("%s: line %d columns %d-%d = %q, want %q", tt.name, tt.line, tt.start+1, tt.end, line[tt.start:tt.end], tt.want)
+		}
+	}
+}
+
+func TestOMMToTLERejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{"missing mean motion", map[string]string{"MEAN_MOTION": ""}},
+		{"non-numeric eccentricity", map[string]string{"ECCENTRICITY": "abc"}},
+		{"bad epoch", map[string]string{"EPOCH": "yesterday"}},
+		{"catalog number past Alpha-5", map[string]string{"NORAD_CAT_ID": "340000"}},
+		{"mean motion dot too large", map[string]string{"MEAN_MOTION_DOT": "1.5"}},
+		{"not sgp4 mean elements", map[string]string{"MEAN_ELEMENT_THEORY": "DSST"}},
+	}
+
+	for _, tt := range tests {
+		fields := issOMM()
+		for k, v := range tt.fields {
+			fields[k] = v
+		}
+		if _, _, err := OMMToTLE(fields); err == nil {
+			t.Errorf("%s: OMMToTLE succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestAlpha5RoundTrip(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "00000"},
+		{25544, "25544"},
+		{99999, "99999"},
+		{100000, "A0000"},
+		{179999, "H9999"},
+		{180000, "J0000"},
+		{229999, "N9999"},
+		{230000, "P0000"},
+		{270001, "T0001"},
+		{339999, "Z9999"},
+	}
+
+	for _, tt := range tests {
+		got, err := Alpha5(tt.n)
+		if err != nil || got != tt.want {
+			t.Errorf("Alpha5(%d) = %q, %v, want %q", tt.n, got, err, tt.want)
+			continue
+		}
+		n, err := ParseAlpha5(got)
+		if err != nil || n != tt.n {
+			t.Errorf("ParseAlpha5(%q) = %d, %v, want %d", got, n, err, tt.n)
+		}
+	}
+}
+
+func TestAlpha5Rejects(t *testing.T) {
+	for _, n := range []int{-1, 340000} {
+		if s, err := Alpha5(n); err == nil {
+			t.Errorf("Alpha5(%d) = %q, want an error", n, s)
+		}
+	}
+	// I and O are skipped so they cannot be mistaken for 1 and 0
+	for _, s := range []string{"I0000", "O0000", "", "A000", "A00000", "AB000"} {
+		if n, err := ParseAlpha5(s); err == nil {
+			t.Errorf("ParseAlpha5(%q) = %d, want an error", s, n)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+// orbit sources a satellite can be driven by, selected per satellite in the fleet roster
+const (
+	OrbitSourceSGP4 = "sgp4"
+	OrbitSourceOEM  = "oem"
+)
+
+var orbitSources = []string{OrbitSourceSGP4, OrbitSourceOEM}
+
+// Orbit propagation model of a satellite. SGP4 is always initialized from the satellite's tle,
+// Ephemeris is set when the roster selects oem and an ephemeris for the satellite is loaded.
+// history is set by SatelliteOrbitOver to switch between archived tles across a window
+type Orbit struct {
+	SGP4      satellite.Satellite
+	Ephemeris *Ephemeris
+	history   []tleOrbit
+}
+
+// tleOrbit sgp4 initialized from an archived tle, used up to and including until, the time halfway to the next
+// archived epoch. until is zero for the newest tle
+type tleOrbit struct {
+	until time.Time
+	sgp4  satellite.Satellite
+}
+
+// NewOrbit initializes sgp4 from a tle and attaches the satellite's OEM ephemeris when its roster source is oem
+func NewOrbit(satID string, line1 string, line2 string, roster map[string]RosterEntry) Orbit {
+	orbit := Orbit{SGP4: TLEToSGP4(line1, line2)}
+	if roster[satID].Source == OrbitSourceOEM {
+		orbit.Ephemeris = LoadedEphemerides.Get(satID)
+	}
+
+	return orbit
+}
+
+// Propagate returns the eci position, eci velocity and greenwich sidereal time of the orbit at t and the source that produced them.
+// The ephemeris is used wherever it covers t, sgp4 everywhere else, from the archived tle closest to t when the orbit has a history
+func (o Orbit) Propagate(t time.Time) (satellite.Vector3, satellite.Vector3, float64, string) {
+	if o.Ephemeris != nil {
+		if pos, vel, gmst, ok := o.Ephemeris.StateAt(t); ok {
+			return pos, vel, gmst, OrbitSourceOEM
+		}
+	}
+
+	utc := t.UTC()
+	y, m, d := utc.Date()
+	h, min, sec := utc.Clock()
+	gmst := satellite.GSTimeFromDate(y, int(m), d, h, min, sec)
+	sgp4 := o.SGP4
+	for _, archived := range o.history {
+		sgp4 = archived.sgp4
+		if archived.until.IsZero() || !t.After(archived.until) {
+			break
+		}
+	}
+	pos, vel := satellite.Propagate(sgp4, y, int(m), d, h, min, sec)
+
+	return pos, vel, gmst, OrbitSourceSGP4
+}
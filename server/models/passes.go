@@ -0,0 +1,209 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// passSearchStep coarse step used to find elevation threshold crossings before refining them
+const passSearchStep = 60 * time.Second
+
+// Pass single contact between a target and a satellite
+type Pass struct {
+	TargetID      string  `json:"targetID"`
+	SatelliteID   string  `json:"satelliteID"`
+	AOS           string  `json:"aos"`
+	LOS           string  `json:"los"`
+	TCA           string  `json:"tca"`
+	MaxElevation  float64 `json:"maxElevation"`
+	AOSElevation  float64 `json:"aosElevation"`
+	LOSElevation  float64 `json:"losElevation"`
+	StartsInPass  bool    `json:"startsInPass"`
+	EndsInPass    bool    `json:"endsInPass"`
+	DurationInSec float64 `json:"durationInSec"`
+}
+
+// PassType graphql object for predicted passes
+var PassType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Pass",
+	Fields: graphql.Fields{
+		"targetId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"satelliteId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"aos": &graphql.Field{
+			Type:        graphql.String,
+			Description: "acquisition of signal, when elevation rises above the target's minElTlmAOS",
+		},
+		"los": &graphql.Field{
+			Type:        graphql.String,
+			Description: "loss of signal, when elevation drops below the target's minElTlmLOS",
+		},
+		"tca": &graphql.Field{
+			Type:        graphql.String,
+			Description: "time of maximum elevation",
+		},
+		"maxElevation": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"aosElevation": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"losElevation": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"startsInPass": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "satellite was already visible at the start of the window, so aos is the window start",
+		},
+		"endsInPass": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "satellite was still visible at the end of the window, so los is the window end",
+		},
+		"durationInSec": &graphql.Field{
+			Type: graphql.Float,
+		},
+	},
+})
+
+// PredictPasses finds every pass of the satellite over the observer between start and end.
+// A pass starts when elevation rises above aosEl and ends when it drops below losEl.
+func PredictPasses(o Observer, aosEl float64, losEl float64, sat Orbit, start time.Time, end time.Time) ([]Pass, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+	if int(end.Sub(start)/passSearchStep)+1 > MaxGroundTrackPoints {
+		return nil, fmt.Errorf("window is too long for pass prediction")
+	}
+
+	elevation := func(t time.Time) float64 {
+		return ElevationAt(o, sat, t)
+	}
+
+	return findPasses(elevation, aosEl, losEl, start, end), nil
+}
+
+// findPasses steps elevation through the window by passSearchStep and refines each threshold crossing and maximum
+func findPasses(elevation func(time.Time) float64, aosEl float64, losEl float64, start time.Time, end time.Time) []Pass {
+	passes := make([]Pass, 0)
+	var current *Pass
+	var aosTime, tcaTime time.Time
+
+	prevTime := start
+	if startEl := elevation(start); startEl >= aosEl {
+		current = &Pass{StartsInPass: true, AOSElevation: startEl, MaxElevation: startEl}
+		aosTime = start
+		tcaTime = start
+	}
+
+	for t := start.Add(passSearchStep); ; t = t.Add(passSearchStep) {
+		if t.After(end) {
+			t = end
+		}
+		el := elevation(t)
+
+		if current == nil && el >= aosEl {
+			aosTime = refineCrossing(elevation, prevTime, t, aosEl, true)
+			tcaTime = t
+			current = &Pass{AOSElevation: elevation(aosTime), MaxElevation: el}
+		} else if current != nil && el < losEl {
+			losTime := refineCrossing(elevation, prevTime, t, losEl, false)
+			passes = append(passes, finishPass(current, elevation, aosTime, losTime, tcaTime))
+			current = nil
+		}
+		if current != nil && el > current.MaxElevation {
+			current.MaxElevation = el
+			tcaTime = t
+		}
+
+		if !t.Before(end) {
+			break
+		}
+		prevTime = t
+	}
+
+	if current != nil {
+		current.EndsInPass = true
+		passes = append(passes, finishPass(current, elevation, aosTime, end, tcaTime))
+	}
+
+	return passes
+}
+
+// finishPass refines the time of maximum elevation and fills in the pass times
+func finishPass(p *Pass, elevation func(time.Time) float64, aos time.Time, los time.Time, tca time.Time) Pass {
+	lo := tca.Add(-passSearchStep)
+	if lo.Before(aos) {
+		lo = aos
+	}
+	hi := tca.Add(passSearchStep)
+	if hi.After(los) {
+		hi = los
+	}
+	tca = refineMaximum(elevation, lo, hi)
+
+	p.AOS = aos.UTC().Format(time.RFC3339)
+	p.LOS = los.UTC().Format(time.RFC3339)
+	p.TCA = tca.UTC().Format(time.RFC3339)
+	p.MaxElevation = elevation(tca)
+	p.LOSElevation = elevation(los)
+	p.DurationInSec = los.Sub(aos).Seconds()
+
+	return *p
+}
+
+// refineCrossing bisects between lo and hi down to a second to find when elevation crosses the threshold
+func refineCrossing(elevation func(time.Time) float64, lo time.Time, hi time.Time, threshold float64, rising bool) time.Time {
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		above := elevation(mid) >= threshold
+		if above == rising {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+// refineMaximum ternary searches between lo and hi down to a second for the highest elevation
+func refineMaximum(elevation func(time.Time) float64, lo time.Time, hi time.Time) time.Time {
+	for hi.Sub(lo) > 2*time.Second {
+		third := hi.Sub(lo) / 3
+		m1 := lo.Add(third)
+		m2 := hi.Add(-third)
+		if elevation(m1) < elevation(m2) {
+			lo = m1
+		} else {
+			hi = m2
+		}
+	}
+	return lo.Add(hi.Sub(lo) / 2)
+}
+
+// GetPasses predicts passes of a FLEET satellite over a TARGETS target using the target's AOS and LOS elevation thresholds
+func GetPasses(targetID string, satID string, start time.Time, end time.Time) ([]Pass, error) {
+	target, ok := GetTargetFeature(targetID)
+	if !ok {
+		return nil, fmt.Errorf("target %s not found", targetID)
+	}
+	sat, ok := SatelliteOrbitOver(satID, start, end)
+	if !ok {
+		return nil, fmt.Errorf("satellite %s not found", satID)
+	}
+
+	passes, err := PredictPasses(TargetObserver(target), target.Properties.MinElTlmAOS, target.Properties.MinElTlmLOS, sat, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for i := range passes {
+		passes[i].TargetID = targetID
+		passes[i].SatelliteID = satID
+	}
+
+	return passes, nil
+}
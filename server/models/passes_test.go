@@ -0,0 +1,120 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var passEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// peakElevation elevation of passes culminating at 40 degrees at each peak, falling off as a parabola 60 seconds wide
+func peakElevation(peaks ...time.Duration) func(time.Time) float64 {
+	return func(t time.Time) float64 {
+		el := -90.0
+		for _, peak := range peaks {
+			x := t.Sub(passEpoch.Add(peak)).Seconds() / 60
+			el = math.Max(el, 40-x*x)
+		}
+		return el
+	}
+}
+
+// peakCrossing offset from a peak at which its parabola crosses the threshold
+func peakCrossing(threshold float64) time.Duration {
+	return time.Duration(math.Sqrt(40-threshold) * 60 * float64(time.Second))
+}
+
+func TestRefineCrossing(t *testing.T) {
+	// elevation rising through 0 at 100.5 seconds and falling through it at 250.25 seconds
+	elevation := func(t time.Time) float64 {
+		s := t.Sub(passEpoch).Seconds()
+		return math.Min(s-100.5, 250.25-s)
+	}
+	tests := []struct {
+		name   string
+		lo     time.Duration
+		hi     time.Duration
+		rising bool
+		want   time.Duration
+	}{
+		{"rising", 60 * time.Second, 120 * time.Second, true, 100500 * time.Millisecond},
+		{"falling", 240 * time.Second, 300 * time.Second, false, 250250 * time.Millisecond},
+		{"already a second apart", 100 * time.Second, 101 * time.Second, true, 100500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		got := refineCrossing(elevation, passEpoch.Add(tt.lo), passEpoch.Add(tt.hi), 0, tt.rising).Sub(passEpoch)
+		// bisection returns the end of a bracket of at most a second that holds the crossing
+		if got < tt.want || got > tt.want+time.Second {
+			t.Errorf("%s: refineCrossing = %v, want within a second after %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefineMaximum(t *testing.T) {
+	got := refineMaximum(peakElevation(95*time.Second), passEpoch, passEpoch.Add(180*time.Second)).Sub(passEpoch)
+	if math.Abs((got - 95*time.Second).Seconds()) > 1 {
+		t.Errorf("refineMaximum = %v, want within a second of 1m35s", got)
+	}
+}
+
+func TestFindPasses(t *testing.T) {
+	type wantPass struct {
+		aos, los, tca            time.Duration
+		startsInPass, endsInPass bool
+	}
+	rise, set := peakCrossing(10), peakCrossing(5)
+	tests := []struct {
+		name       string
+		peaks      []time.Duration
+		start, end time.Duration
+		aosEl      float64
+		want       []wantPass
+	}{
+		{"one pass", []time.Duration{30 * time.Minute}, 0, time.Hour, 10,
+			[]wantPass{{30*time.Minute - rise, 30*time.Minute + set, 30 * time.Minute, false, false}}},
+		{"window starts in the pass", []time.Duration{30 * time.Minute}, 28 * time.Minute, time.Hour, 10,
+			[]wantPass{{28 * time.Minute, 30*time.Minute + set, 30 * time.Minute, true, false}}},
+		{"window ends in the pass", []time.Duration{30 * time.Minute}, 0, 31 * time.Minute, 10,
+			[]wantPass{{30*time.Minute - rise, 31 * time.Minute, 30 * time.Minute, false, true}}},
+		{"two passes", []time.Duration{30 * time.Minute, 90 * time.Minute}, 0, 2 * time.Hour, 10,
+			[]wantPass{
+				{30*time.Minute - rise, 30*time.Minute + set, 30 * time.Minute, false, false},
+				{90*time.Minute - rise, 90*time.Minute + set, 90 * time.Minute, false, false},
+			}},
+		{"never above the aos threshold", []time.Duration{30 * time.Minute}, 0, time.Hour, 45, []wantPass{}},
+		{"no passes", []time.Duration{}, 0, time.Hour, 10, []wantPass{}},
+	}
+
+	// pass times are reported to the second, so allow a second of bisection and a second of truncation
+	near := func(got string, want time.Duration) bool {
+		t, err := time.Parse(time.RFC3339, got)
+		return err == nil && math.Abs(t.Sub(passEpoch.Add(want)).Seconds()) <= 2
+	}
+	for _, tt := range tests {
+		elevation := peakElevation(tt.peaks...)
+		passes := findPasses(elevation, tt.aosEl, 5, passEpoch.Add(tt.start), passEpoch.Add(tt.end))
+		if len(passes) != len(tt.want) {
+			t.Errorf("%s: got %d passes, want %d", tt.name, len(passes), len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			p := passes[i]
+			if !near(p.AOS, want.aos) || !near(p.LOS, want.los) || !near(p.TCA, want.tca) {
+				t.Errorf("%s: pass %d aos %s los %s tca %s, want %v, %v and %v after %s", tt.name, i, p.AOS, p.LOS, p.TCA,
+					want.aos, want.los, want.tca, passEpoch.Format(time.RFC3339))
+			}
+			if p.StartsInPass != want.startsInPass || p.EndsInPass != want.endsInPass {
+				t.Errorf("%s: pass %d startsInPass %v endsInPass %v, want %v and %v", tt.name, i,
+					p.StartsInPass, p.EndsInPass, want.startsInPass, want.endsInPass)
+			}
+			if math.Abs(p.MaxElevation-40) > 0.01 {
+				t.Errorf("%s: pass %d max elevation %g, want 40", tt.name, i, p.MaxElevation)
+			}
+			if math.Abs(p.DurationInSec-(want.los-want.aos).Seconds()) > 2 {
+				t.Errorf("%s: pass %d lasts %gs, want %gs", tt.name, i, p.DurationInSec, (want.los - want.aos).Seconds())
+			}
+		}
+	}
+}
@@ -2,13 +2,14 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/graphql-go/graphql"
 )
 
 // http://localhost:8080/satellite?query={satellite(id:%22M007%22){id,latitude,longitude,velocity,altitude,mission{id,config,gatewayID,gatewayOBAnt,gatewayMaxPointingTime,beams{id,epcs,targetOBAnt,targetMaxPointingTime,camp,campMode,campGain,ldla,ldlaMode,ldlaFcaGain,ldlaGcaGain,ldlaScaGain}}}}
 // http://localhost:8080/target?query={target(id:%2235%22){geometry{coordinates},properties{shortName}}
-// http://localhost:8080/catseye?query={catseye(id:%2210%22){geometry{coordinates},properties{subregion}}
+// http://localhost:8080/catseye?query={catseye(id:%2210%22){geometry{type,polygons},properties{subregion}}
 // http://localhost:8080/targets?query={targets{geometry{coordinates},properties{shortName}}}
 
 // RootQuery main graphql query for schema
@@ -22,10 +23,21 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 				"id": &graphql.ArgumentConfig{
 					Type: graphql.String,
 				},
+				"at": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "RFC3339 time to propagate the satellite to instead of its last known position",
+				},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				at, hasAt, err := getTimeArg(params.Args, "at")
+				if err != nil {
+					return nil, err
+				}
 				idQuery, isOK := params.Args["id"].(string)
 				if isOK {
+					if hasAt {
+						return GetSatellitePositionAt(idQuery, at), nil
+					}
 					livesat := GetSatellitePosition(idQuery)
 					return livesat, nil
 				}
@@ -35,9 +47,22 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 		},
 		"satelliteFeatureCollection": &graphql.Field{
 			Type:        SatelliteFeatureCollectionType,
-			Description: "Get all targets and their properties",
+			Description: "Get all satellites and their properties",
+			Args: graphql.FieldConfigArgument{
+				"at": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "RFC3339 time to propagate the satellites to instead of their last known positions",
+				},
+			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				at, hasAt, err := getTimeArg(params.Args, "at")
+				if err != nil {
+					return nil, err
+				}
 				satellites := GetMovingSatellites()
+				if hasAt {
+					satellites = GetSatellitesAt(at)
+				}
 				satelliteFeatureCollection := SatelliteFeatureCollection{
 					Type:     "featureCollection",
 					Features: satellites,
@@ -45,6 +70,231 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 				return satelliteFeatureCollection, nil
 			},
 		},
+		"groundTrack": &graphql.Field{
+			Type:        GroundTrackType,
+			Description: "Get a satellite's ground track between start and end, split at the antimeridian",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"start": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 start of the window",
+				},
+				"end": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 end of the window",
+				},
+				"step": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: 60,
+					Description:  "seconds between propagated points",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				start, end, err := getWindowArgs(params.Args)
+				if err != nil {
+					return nil, err
+				}
+				step, _ := params.Args["step"].(int)
+				idQuery, _ := params.Args["id"].(string)
+
+				return BuildGroundTrackFeature(idQuery, start, end, time.Duration(step)*time.Second)
+			},
+		},
+		"passes": &graphql.Field{
+			Type:        graphql.NewList(PassType),
+			Description: "Predict passes of a satellite over a target using the target's AOS and LOS elevation thresholds",
+			Args: graphql.FieldConfigArgument{
+				"targetId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"satelliteId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"start": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 start of the window",
+				},
+				"end": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 end of the window",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				start, end, err := getWindowArgs(params.Args)
+				if err != nil {
+					return nil, err
+				}
+				targetID, _ := params.Args["targetId"].(string)
+				satID, _ := params.Args["satelliteId"].(string)
+
+				return GetPasses(targetID, satID, start, end)
+			},
+		},
+		"lookAngles": &graphql.Field{
+			Type:        LookAnglesType,
+			Description: "Get azimuth, elevation, slant range and range rate from a target to a satellite",
+			Args: graphql.FieldConfigArgument{
+				"targetId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"satelliteId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"at": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "RFC3339 time of the look angles, defaults to now",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				at, hasAt, err := getTimeArg(params.Args, "at")
+				if err != nil {
+					return nil, err
+				}
+				if !hasAt {
+					at = time.Now()
+				}
+				targetID, _ := params.Args["targetId"].(string)
+				satID, _ := params.Args["satelliteId"].(string)
+
+				return GetLookAngles(targetID, satID, at)
+			},
+		},
+		"linkSeries": &graphql.Field{
+			Type:        graphql.NewList(LinkSampleType),
+			Description: "Get doppler shift, slant range, free-space path loss and delay between a target and a satellite over a window",
+			Args: graphql.FieldConfigArgument{
+				"targetId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"satelliteId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"start": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 start of the window",
+				},
+				"end": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "RFC3339 end of the window",
+				},
+				"step": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: 10,
+					Description:  "seconds between samples",
+				},
+				"frequency": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.Float),
+					Description: "carrier frequency in hertz",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				start, end, err := getWindowArgs(params.Args)
+				if err != nil {
+					return nil, err
+				}
+				step, _ := params.Args["step"].(int)
+				frequency, _ := params.Args["frequency"].(float64)
+				targetID, _ := params.Args["targetId"].(string)
+				satID, _ := params.Args["satelliteId"].(string)
+
+				return BuildLinkSeries(targetID, satID, start, end, time.Duration(step)*time.Second, frequency)
+			},
+		},
+		"visibility": &graphql.Field{
+			Type:        graphql.NewList(SatelliteVisibilityType),
+			Description: "Get the targets each satellite can see above the targets' AOS elevation",
+			Args: graphql.FieldConfigArgument{
+				"at": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "RFC3339 time to propagate the satellites to, now when omitted",
+				},
+				"satelliteId": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "only return the row for this satellite",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				at, hasAt, err := getTimeArg(params.Args, "at")
+				if err != nil {
+					return nil, err
+				}
+				if !hasAt {
+					at = time.Now()
+				}
+				visibility := GetVisibility(at)
+
+				satID, isOK := params.Args["satelliteId"].(string)
+				if isOK {
+					filtered := make([]SatelliteVisibility, 0)
+					for _, v := range visibility {
+						if v.SatelliteID == satID {
+							filtered = append(filtered, v)
+						}
+					}
+					return filtered, nil
+				}
+
+				return visibility, nil
+			},
+		},
+		"footprint": &graphql.Field{
+			Type:        FootprintType,
+			Description: "Get the ground visible from a satellite above an elevation mask",
+			Args: func() graphql.FieldConfigArgument {
+				args := footprintArgs()
+				args["id"] = &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				}
+				return args
+			}(),
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				idQuery, _ := params.Args["id"].(string)
+
+				return resolveFootprint(idQuery, GetSatellitePosition(idQuery), params.Args)
+			},
+		},
+		"fleet": &graphql.Field{
+			Type:        graphql.NewList(RosterEntryType),
+			Description: "Get the fleet roster with each satellite's role, block and beamplan file",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return GetRosterList(), nil
+			},
+		},
+		"tleHistory": &graphql.Field{
+			Type:        graphql.NewList(ArchivedTLEType),
+			Description: "Get every tle a satellite has been ingested with, oldest epoch first",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+
+				return GetTLEHistory(id), nil
+			},
+		},
+		"ingestReport": &graphql.Field{
+			Type:        IngestReportType,
+			Description: "Get the validation report of the latest tle file or upload",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				report, ok := GetIngestReport()
+				if !ok {
+					return nil, nil
+				}
+
+				return report, nil
+			},
+		},
+		"beamplans": &graphql.Field{
+			Type:        graphql.NewList(BeamplanVersionType),
+			Description: "Get every uploaded beamplan version, oldest first",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return GetBeamplanVersions(), nil
+			},
+		},
 		"target": &graphql.Field{
 			Type:        TargetType,
 			Description: "Get a single target and its properties",
@@ -113,3 +363,37 @@ func ExecuteQuery(query string, params graphql.Params) *graphql.Result {
 	}
 	return result
 }
+
+// getTimeArg parses an optional RFC3339 time argument
+func getTimeArg(args map[string]interface{}, name string) (time.Time, bool, error) {
+	value, isOK := args[name].(string)
+	if !isOK || value == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s must be an RFC3339 time: %v", name, err)
+	}
+
+	return t, true, nil
+}
+
+// getWindowArgs parses the required RFC3339 start and end arguments of a time window
+func getWindowArgs(args map[string]interface{}) (time.Time, time.Time, error) {
+	start, hasStart, err := getTimeArg(args, "start")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, hasEnd, err := getTimeArg(args, "end")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !hasStart || !hasEnd {
+		return time.Time{}, time.Time{}, fmt.Errorf("start and end are required")
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must not be before start")
+	}
+
+	return start, end, nil
+}
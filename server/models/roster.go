@@ -0,0 +1,209 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/boltdb/bolt"
+	"github.com/graphql-go/graphql"
+)
+
+// RosterEntry struct modeling a satellite in the fleet roster file.
+// The roster is a csv in the data directory whose name contains ROSTER, with the columns
+// satellite id, role (active or spare), block, the file name of the BEAMPLAN_LONGFORMAT file it flies,
+// and an optional orbit source (sgp4 or oem, sgp4 when empty). examples/ROSTER.csv lists the built-in fleet
+// that is flown when no roster file is loaded
+type RosterEntry struct {
+	SatelliteID string `json:"satelliteID"`
+	Role        string `json:"role"`
+	Block       string `json:"block"`
+	Beamplan    string `json:"beamplan"`
+	Source      string `json:"source"`
+}
+
+// RosterEntryType graphql object for fleet roster entries
+var RosterEntryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RosterEntry",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(RosterEntry)
+
+				return s.SatelliteID, nil
+			},
+		},
+		"role": &graphql.Field{
+			Type: graphql.String,
+		},
+		"block": &graphql.Field{
+			Type: graphql.String,
+		},
+		"beamplan": &graphql.Field{
+			Type: graphql.String,
+		},
+		"source": &graphql.Field{
+			Type:        graphql.String,
+			Description: "orbit source selected for the satellite, sgp4 or oem",
+		},
+	},
+})
+
+// roster roles accepted in the roster file
+var rosterRoles = []string{"active", "spare"}
+
+// builtin fleet flown when no roster file is loaded, as it was assigned before roster files existed
+var (
+	builtinSpares = []string{"M002", "M004", "M005"}
+	builtinActive = []string{"M001", "M003", "M006", "M007", "M008", "M009", "M010", "M011", "M012"}
+	builtinBlock3 = []string{"M013", "M014", "M015", "M016"}
+)
+
+// builtinRoster roster of the builtin fleet. Beamplan files are assigned by name: a file naming mute to the spares,
+// M001 and M013 to those satellites, B3 to the rest of block 3, and any other beamplan file to the rest of the fleet
+func builtinRoster(bpfiles map[string]string) map[string]RosterEntry {
+	names := make([]string, 0)
+	for name := range bpfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	special := []string{"mute", "M001", "M013", "B3"}
+	plans := make(map[string]string, 0)
+	for _, name := range names {
+		matched := false
+		for _, key := range special {
+			if strings.Contains(name, key) {
+				matched = true
+				if _, ok := plans[key]; !ok {
+					plans[key] = name
+				}
+			}
+		}
+		if _, ok := plans["active"]; !ok && !matched {
+			plans["active"] = name
+		}
+	}
+
+	roster := make(map[string]RosterEntry, 0)
+	add := func(sats []string, role string, block string, plan string) {
+		for _, sat := range sats {
+			beamplan := plans[plan]
+			if p, ok := plans[sat]; ok {
+				beamplan = p
+			}
+			roster[sat] = RosterEntry{sat, role, block, beamplan, OrbitSourceSGP4}
+		}
+	}
+	add(builtinSpares, "spare", "", "mute")
+	add(builtinActive, "active", "", "active")
+	add(builtinBlock3, "active", "B3", "B3")
+
+	return roster
+}
+
+// FillRosterBucket replaces the ROSTER bucket with the entries of the given fleet roster files in one transaction.
+// The bucket is left unchanged when a file cannot be read
+func FillRosterBucket(files []string) error {
+	entries := make(map[string]interface{}, 0)
+	for _, f := range files {
+		// the source column is optional, so rows may have 4 or 5 columns
+		_, records, err := ReadCSVFile(f, -1)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			entry, err := buildRosterEntry(record)
+			if err != nil {
+				fmt.Println("Skipping roster entry:", err)
+				continue
+			}
+			entries[entry.SatelliteID] = entry
+		}
+	}
+
+	err := DB.Update(func(tx *bolt.Tx) error {
+		return replaceBucket(tx, "ROSTER", entries)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Roster bucket filled")
+
+	return nil
+}
+
+func buildRosterEntry(r []string) (RosterEntry, error) {
+	if len(r) < 4 {
+		return RosterEntry{}, fmt.Errorf("expected 4 columns, got %d", len(r))
+	}
+	entry := RosterEntry{
+		SatelliteID: strings.TrimSpace(r[0]),
+		Role:        strings.ToLower(strings.TrimSpace(r[1])),
+		Block:       strings.TrimSpace(r[2]),
+		Beamplan:    strings.TrimSpace(r[3]),
+		Source:      OrbitSourceSGP4,
+	}
+	if len(r) > 4 && strings.TrimSpace(r[4]) != "" {
+		entry.Source = strings.ToLower(strings.TrimSpace(r[4]))
+	}
+	if entry.SatelliteID == "" {
+		return RosterEntry{}, fmt.Errorf("missing satellite id")
+	}
+	if !helpers.StringInSlice(entry.Role, rosterRoles) {
+		return RosterEntry{}, fmt.Errorf("%s has unknown role %q", entry.SatelliteID, r[1])
+	}
+	if entry.Beamplan == "" {
+		return RosterEntry{}, fmt.Errorf("%s has no beamplan file", entry.SatelliteID)
+	}
+	if !helpers.StringInSlice(entry.Source, orbitSources) {
+		return RosterEntry{}, fmt.Errorf("%s has unknown orbit source %q", entry.SatelliteID, r[4])
+	}
+
+	return entry, nil
+}
+
+// GetRoster pulls the fleet roster from the ROSTER bucket keyed by satellite id
+func GetRoster() map[string]RosterEntry {
+	roster := make(map[string]RosterEntry, 0)
+	err := DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("ROSTER"))
+		b.ForEach(func(k, v []byte) error {
+			var entry RosterEntry
+			json.Unmarshal(v, &entry)
+			roster[string(k)] = entry
+			return nil
+		})
+		return nil
+	})
+	helpers.PanicErrors(err)
+
+	return roster
+}
+
+// GetRosterList returns the fleet roster sorted by satellite id
+func GetRosterList() []RosterEntry {
+	entries := make([]RosterEntry, 0)
+	for _, entry := range GetRoster() {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SatelliteID < entries[j].SatelliteID
+	})
+
+	return entries
+}
+
+// GetRosterIDs returns the satellite ids in the fleet roster in order
+func GetRosterIDs() []string {
+	ids := make([]string, 0)
+	for _, entry := range GetRosterList() {
+		ids = append(ids, entry.SatelliteID)
+	}
+
+	return ids
+}
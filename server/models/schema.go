@@ -4,5 +4,7 @@ import "github.com/graphql-go/graphql"
 
 // Schema graphql schema
 var Schema, _ = graphql.NewSchema(graphql.SchemaConfig{
-	Query: RootQuery,
+	Query:        RootQuery,
+	Mutation:     RootMutation,
+	Subscription: RootSubscription,
 })
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// SatelliteHub fans out each tick of propagated satellites to every subscriber
+type SatelliteHub struct {
+	mu          sync.RWMutex
+	subscribers map[chan []SatelliteFeature]struct{}
+}
+
+// SatelliteTicks hub fed by UpdateSatPos on every tick of the app ticker
+var SatelliteTicks = NewSatelliteHub()
+
+// NewSatelliteHub creates an empty satellite hub
+func NewSatelliteHub() *SatelliteHub {
+	return &SatelliteHub{
+		subscribers: make(map[chan []SatelliteFeature]struct{}, 0),
+	}
+}
+
+// Subscribe registers a new channel that receives every published tick
+func (h *SatelliteHub) Subscribe() chan []SatelliteFeature {
+	c := make(chan []SatelliteFeature, 1)
+	h.mu.Lock()
+	h.subscribers[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+// Unsubscribe removes the channel from the hub and closes it
+func (h *SatelliteHub) Unsubscribe(c chan []SatelliteFeature) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[c]; ok {
+		delete(h.subscribers, c)
+		close(c)
+	}
+	h.mu.Unlock()
+}
+
+// Publish sends the satellites to every subscriber, skipping subscribers still busy with the previous tick
+func (h *SatelliteHub) Publish(s []SatelliteFeature) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.subscribers {
+		select {
+		case c <- s:
+		default:
+		}
+	}
+}
+
+// subscribeSatellites forwards hub ticks to graphql until the subscription context is done
+func subscribeSatellites(params graphql.ResolveParams, filter func([]SatelliteFeature) interface{}) (interface{}, error) {
+	ticks := SatelliteTicks.Subscribe()
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer SatelliteTicks.Unsubscribe(ticks)
+		for {
+			select {
+			case <-params.Context.Done():
+				return
+			case sats, more := <-ticks:
+				if !more {
+					return
+				}
+				select {
+				case out <- filter(sats):
+				case <-params.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// RootSubscription main graphql subscription for schema
+var RootSubscription = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RootSubscription",
+	Fields: graphql.Fields{
+		"satellite": &graphql.Field{
+			Type:        SatelliteType,
+			Description: "Receive a single satellite, its location, and current mission on every tick",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Subscribe: func(params graphql.ResolveParams) (interface{}, error) {
+				idQuery, _ := params.Args["id"].(string)
+				return subscribeSatellites(params, func(sats []SatelliteFeature) interface{} {
+					for _, s := range sats {
+						if s.Properties.ID == idQuery {
+							return s
+						}
+					}
+					return SatelliteFeature{}
+				})
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source, nil
+			},
+		},
+		"satelliteFeatureCollection": &graphql.Field{
+			Type:        SatelliteFeatureCollectionType,
+			Description: "Receive all satellites and their properties on every tick",
+			Subscribe: func(params graphql.ResolveParams) (interface{}, error) {
+				return subscribeSatellites(params, func(sats []SatelliteFeature) interface{} {
+					return SatelliteFeatureCollection{
+						Type:     "featureCollection",
+						Features: sats,
+					}
+				})
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return params.Source, nil
+			},
+		},
+	},
+})
@@ -1,13 +1,10 @@
 package models
 
 import (
-	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"strconv"
+	"strings"
 
 	"github.com/alexmspina/worldmap/server/helpers"
 	"github.com/boltdb/bolt"
@@ -15,15 +12,16 @@ import (
 )
 
 type targetProperties struct {
-	TargetID    string  `json:"targetID"`
-	ShortName   string  `json:"shortName"`
-	Altitude    string  `json:"altitude"`
-	GatewayFlag string  `json:"gatewayFlag"`
-	TTCFlag     string  `json:"ttcFlag"`
-	MinElTlmAOS float64 `json:"minElTlmAOS"`
-	MinElTlmLOS float64 `json:"minElTlmLOS"`
-	LongName    string  `json:"longName"`
-	FileCode    string  `json:"fileCode"`
+	TargetID     string  `json:"targetID"`
+	ShortName    string  `json:"shortName"`
+	Altitude     string  `json:"altitude"`
+	GatewayFlag  string  `json:"gatewayFlag"`
+	TTCFlag      string  `json:"ttcFlag"`
+	MinElTlmAOS  float64 `json:"minElTlmAOS"`
+	MinElTlmLOS  float64 `json:"minElTlmLOS"`
+	LongName     string  `json:"longName"`
+	FileCode     string  `json:"fileCode"`
+	MinElGateway float64 `json:"minElGateway"`
 }
 
 // TargetPropsType graphql type for target feature properties
@@ -57,6 +55,10 @@ var TargetPropsType = graphql.NewObject(graphql.ObjectConfig{
 		"fileCode": &graphql.Field{
 			Type: graphql.String,
 		},
+		"minElGateway": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "elevation mask in degrees below which the target does not serve its zones as a gateway",
+		},
 	},
 })
 
@@ -123,61 +125,55 @@ var TargetFeatureCollectionType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
-// FillTargetsBucket initializes a BoltDB bucket from TARGETS file
-func FillTargetsBucket(f string) error {
-	// Open file from filename
-	r := OpenCSV(f)
-
-	// Get column header values
-	header := getHeader(r)
-	var err error
+// ReadTargetsFile reads the targets of a TARGETS file, skipping rows that fail validateTargetRecord
+func ReadTargetsFile(f string) ([]TargetFeature, error) {
+	// the minElGateway column is optional, so rows may have 11 or 12 columns
+	_, records, err := ReadCSVFile(f, -1)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create list of feature structs
-	for {
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal(err)
+	targets := make([]TargetFeature, 0)
+	for _, record := range records {
+		if len(record) < 11 {
+			fmt.Println("Skipping target", record[0], ": expected 11 columns, got", len(record))
+			continue
 		}
-		if record[0] == header[0] {
+		if err := validateTargetRecord(record); err != nil {
+			fmt.Println("Skipping target", record[0], ":", err)
 			continue
 		}
+		targets = append(targets, buildTargetFeature(record))
+	}
+	return targets, nil
+}
 
-		f := buildTargetFeature(record)
-		id := f.Properties.TargetID
-
-		featureBytes, err := json.MarshalIndent(f, "", "\t")
+// FillTargetsBucket replaces the TARGETS bucket with the targets of the given files and redraws the catseyes, whose
+// gateway masks come from the targets, in one transaction. The bucket is left unchanged when a file cannot be read
+func FillTargetsBucket(files []string) error {
+	targets := make(map[string]interface{}, 0)
+	for _, f := range files {
+		features, err := ReadTargetsFile(f)
 		if err != nil {
-			panic(err)
+			return err
+		}
+		for _, t := range features {
+			targets[t.Properties.TargetID] = t
 		}
-		featureBytes = bytes.Replace(featureBytes, []byte("\\u0026"), []byte("&"), -1)
-		featureBytes = bytes.Trim(featureBytes, "\r")
-
-		err = DB.Update(func(tx *bolt.Tx) error {
-			err = tx.Bucket([]byte("DB")).Bucket([]byte("TARGETS")).Put([]byte(id), featureBytes)
-			if err != nil {
-				return fmt.Errorf("could not fill targets bucket: %v", err)
-			}
-			return nil
-		})
 	}
-	fmt.Println("Targets bucket filled")
-
-	return err
-}
 
-func getHeader(cr *csv.Reader) []string {
-	record, err := cr.Read()
-	if err == io.EOF {
-		return nil
-	}
+	err := DB.Update(func(tx *bolt.Tx) error {
+		if err := replaceBucket(tx, "TARGETS", targets); err != nil {
+			return err
+		}
+		return fillCatseyes(tx)
+	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	fmt.Println("Targets bucket filled")
 
-	return record
+	return nil
 }
 
 func buildTargetFeature(r []string) TargetFeature {
@@ -199,6 +195,9 @@ func buildTargetFeature(r []string) TargetFeature {
 		LongName:    r[9],
 		FileCode:    r[10],
 	}
+	if len(r) > 11 {
+		props.MinElGateway = helpers.ConvertStringToFloat64(r[11])
+	}
 	f := TargetFeature{
 		"Feature",
 		geopoint,
@@ -207,6 +206,14 @@ func buildTargetFeature(r []string) TargetFeature {
 	return f
 }
 
+// targetTrueFlags values of GatewayFlag and TTCFlag that mark a target as a gateway or ttc station
+var targetTrueFlags = []string{"1", "Y", "YES", "TRUE"}
+
+// TargetFlagSet reports whether a target flag column is set
+func TargetFlagSet(flag string) bool {
+	return helpers.StringInSlice(strings.ToUpper(strings.TrimSpace(flag)), targetTrueFlags)
+}
+
 func convertStringToFloat64(s string) float64 {
 	f, _ := strconv.ParseFloat(s, 64)
 	return f
@@ -227,6 +234,19 @@ func GetTarget(s string) TargetFeature {
 	return targetfeature
 }
 
+// GetTargetFeature queries bolt db for the desired target and reports whether it exists
+func GetTargetFeature(s string) (TargetFeature, bool) {
+	var targetfeature TargetFeature
+	found := false
+	err := DB.View(func(tx *bolt.Tx) error {
+		targetfeature, found = targetInTx(tx, s)
+		return nil
+	})
+	helpers.PanicErrors(err)
+
+	return targetfeature, found
+}
+
 // GetTargets grabs all the targets from the TARGETS bucket
 func GetTargets() []TargetFeature {
 	var targetFeatureList []TargetFeature
@@ -244,3 +264,157 @@ func GetTargets() []TargetFeature {
 
 	return targetFeatureList
 }
+
+// targetColumns columns of a TARGETS file row in the order buildTargetFeature reads them
+var targetColumns = []string{"id", "shortName", "latitude", "longitude", "altitude", "gatewayFlag", "ttcFlag", "minElTlmAOS", "minElTlmLOS", "longName", "fileCode", "minElGateway"}
+
+// targetRecord converts a target feature back into a TARGETS file row
+func targetRecord(f TargetFeature) []string {
+	return []string{
+		f.Properties.TargetID,
+		f.Properties.ShortName,
+		strconv.FormatFloat(f.Geometry.Coordinates[1], 'f', -1, 64),
+		strconv.FormatFloat(f.Geometry.Coordinates[0], 'f', -1, 64),
+		f.Properties.Altitude,
+		f.Properties.GatewayFlag,
+		f.Properties.TTCFlag,
+		strconv.FormatFloat(f.Properties.MinElTlmAOS, 'f', -1, 64),
+		strconv.FormatFloat(f.Properties.MinElTlmLOS, 'f', -1, 64),
+		f.Properties.LongName,
+		f.Properties.FileCode,
+		strconv.FormatFloat(f.Properties.MinElGateway, 'f', -1, 64),
+	}
+}
+
+// validateTargetRecord checks a TARGETS row before buildTargetFeature converts it
+func validateTargetRecord(r []string) error {
+	if r[0] == "" {
+		return fmt.Errorf("target id is required")
+	}
+	numbers := make(map[string]float64, 0)
+	for _, i := range []int{2, 3, 7, 8, 11} {
+		// empty elevation thresholds are read as 0 like in the TARGETS file
+		if i >= len(r) || r[i] == "" && i > 3 {
+			continue
+		}
+		n, err := strconv.ParseFloat(r[i], 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", targetColumns[i], r[i])
+		}
+		numbers[targetColumns[i]] = n
+	}
+	if r[4] != "" {
+		if _, err := strconv.ParseFloat(r[4], 64); err != nil {
+			return fmt.Errorf("altitude must be a number of meters, got %q", r[4])
+		}
+	}
+
+	if numbers["latitude"] < -90 || numbers["latitude"] > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90 degrees")
+	}
+	// longitudes east of 180 are accepted and normalized by buildTargetFeature
+	if numbers["longitude"] < -180 || numbers["longitude"] > 360 {
+		return fmt.Errorf("longitude must be between -180 and 360 degrees")
+	}
+	for _, el := range []string{"minElTlmAOS", "minElTlmLOS"} {
+		if numbers[el] < -90 || numbers[el] > 90 {
+			return fmt.Errorf("%s must be between -90 and 90 degrees", el)
+		}
+	}
+	if numbers["minElTlmLOS"] > numbers["minElTlmAOS"] {
+		return fmt.Errorf("minElTlmLOS must not be above minElTlmAOS")
+	}
+	if numbers["minElGateway"] < 0 || numbers["minElGateway"] >= 90 {
+		return fmt.Errorf("minElGateway must be between 0 and 90 degrees")
+	}
+
+	return nil
+}
+
+// targetInTx reads a target from the TARGETS bucket within a transaction
+func targetInTx(tx *bolt.Tx, id string) (TargetFeature, bool) {
+	var targetfeature TargetFeature
+	target := tx.Bucket([]byte("DB")).Bucket([]byte("TARGETS")).Get([]byte(id))
+	if target == nil {
+		return targetfeature, false
+	}
+	json.Unmarshal(target, &targetfeature)
+
+	return targetfeature, true
+}
+
+// CreateTarget validates a TARGETS row and adds it to the TARGETS bucket, redrawing the catseyes, which follow the
+// gateways' masks, in the same transaction
+func CreateTarget(r []string) (TargetFeature, error) {
+	if err := validateTargetRecord(r); err != nil {
+		return TargetFeature{}, err
+	}
+
+	f := buildTargetFeature(r)
+	err := DB.Update(func(tx *bolt.Tx) error {
+		if _, ok := targetInTx(tx, f.Properties.TargetID); ok {
+			return fmt.Errorf("target %s already exists", f.Properties.TargetID)
+		}
+		if err := putFeature(tx, "TARGETS", f.Properties.TargetID, f); err != nil {
+			return err
+		}
+		return fillCatseyes(tx)
+	})
+	if err != nil {
+		return TargetFeature{}, err
+	}
+
+	return f, nil
+}
+
+// UpdateTarget replaces the columns of an existing target that are set in changes, keyed by targetColumns name
+func UpdateTarget(id string, changes map[string]string) (TargetFeature, error) {
+	var f TargetFeature
+	err := DB.Update(func(tx *bolt.Tx) error {
+		existing, ok := targetInTx(tx, id)
+		if !ok {
+			return fmt.Errorf("target %s not found", id)
+		}
+
+		r := targetRecord(existing)
+		for i, column := range targetColumns {
+			if value, isSet := changes[column]; isSet && i > 0 {
+				r[i] = value
+			}
+		}
+		if err := validateTargetRecord(r); err != nil {
+			return err
+		}
+
+		f = buildTargetFeature(r)
+		if err := putFeature(tx, "TARGETS", f.Properties.TargetID, f); err != nil {
+			return err
+		}
+		return fillCatseyes(tx)
+	})
+	if err != nil {
+		return TargetFeature{}, err
+	}
+
+	return f, nil
+}
+
+// DeleteTarget removes a target from the TARGETS bucket, redraws the catseyes and returns the target
+func DeleteTarget(id string) (TargetFeature, error) {
+	var existing TargetFeature
+	err := DB.Update(func(tx *bolt.Tx) error {
+		var ok bool
+		if existing, ok = targetInTx(tx, id); !ok {
+			return fmt.Errorf("target %s not found", id)
+		}
+		if err := tx.Bucket([]byte("DB")).Bucket([]byte("TARGETS")).Delete([]byte(id)); err != nil {
+			return err
+		}
+		return fillCatseyes(tx)
+	})
+	if err != nil {
+		return TargetFeature{}, err
+	}
+
+	return existing, nil
+}
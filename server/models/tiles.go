@@ -0,0 +1,414 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+)
+
+// MaxTileZoom deepest zoom level tiles are served for
+const MaxTileZoom = 22
+
+// tileBuffer tile coordinates kept outside each edge so clipped lines and polygons join up across tiles
+const tileBuffer = 64
+
+// tileSimplifyTolerance Douglas-Peucker tolerance in tile coordinates. Geometry is simplified after projecting
+// into the requested tile, so the same tolerance removes more vertices the further out the zoom
+const tileSimplifyTolerance = 4.0
+
+// maxMercatorLat latitude limit of the web mercator projection
+const maxMercatorLat = 85.0511287798
+
+// TileTrackWindow and TileTrackStep span and spacing of the satellite ground tracks in the tracks layer, starting now
+var (
+	TileTrackWindow = 6 * time.Hour
+	TileTrackStep   = 2 * time.Minute
+)
+
+// TileLayers vector tile layers served under /tiles, keyed by the layer name used in the url
+var TileLayers = map[string]func() []GeoJSONFeature{
+	"targets":    TargetsGeoJSON,
+	"catseyes":   CatseyesGeoJSON,
+	"satellites": SatellitesGeoJSON,
+	"tracks":     TracksGeoJSON,
+}
+
+// tileTracks ground tracks shared by every tile request for a minute, propagating the fleet per tile would be too slow
+type tileTracks struct {
+	mu       sync.Mutex
+	built    time.Time
+	features []GeoJSONFeature
+}
+
+var cachedTracks = &tileTracks{}
+
+// TracksGeoJSON ground tracks of the satellites in the SATPOS bucket over TileTrackWindow from now, split at the antimeridian
+func TracksGeoJSON() []GeoJSONFeature {
+	cachedTracks.mu.Lock()
+	defer cachedTracks.mu.Unlock()
+	now := time.Now().UTC()
+	if cachedTracks.features != nil && now.Sub(cachedTracks.built) < time.Minute {
+		return cachedTracks.features
+	}
+
+	features := make([]GeoJSONFeature, 0)
+	for _, s := range GetMovingSatellites() {
+		track, err := BuildGroundTrackFeature(s.Properties.ID, now, now.Add(TileTrackWindow), TileTrackStep)
+		if err != nil {
+			fmt.Println("Could not build ground track of", s.Properties.ID, ":", err)
+			continue
+		}
+		features = append(features, GeoJSONFeature{
+			Type:     "Feature",
+			ID:       s.Properties.ID,
+			Geometry: GeoJSONGeometry{"MultiLineString", track.Geometry.Coordinates},
+			Properties: map[string]interface{}{
+				"id":    s.Properties.ID,
+				"start": track.Properties.Start,
+				"end":   track.Properties.End,
+			},
+		})
+	}
+	sortGeoJSONFeatures(features)
+	cachedTracks.features = features
+	cachedTracks.built = now
+
+	return features
+}
+
+// BuildTile encodes a layer's features clipped to tile z/x/y as a mapbox vector tile
+func BuildTile(layer string, z int, x int, y int) ([]byte, error) {
+	source, ok := TileLayers[layer]
+	if !ok {
+		return nil, fmt.Errorf("unknown layer %s", layer)
+	}
+	if z < 0 || z > MaxTileZoom {
+		return nil, fmt.Errorf("zoom must be within 0 to %d", MaxTileZoom)
+	}
+	if x < 0 || y < 0 || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		return nil, fmt.Errorf("tile %d/%d/%d does not exist", z, x, y)
+	}
+
+	tile := tileProjection{z, x, y}
+	features := make([]MVTFeature, 0)
+	for i, f := range source() {
+		feature, ok := tile.feature(f)
+		if !ok {
+			continue
+		}
+		feature.ID = uint64(i + 1)
+		features = append(features, feature)
+	}
+
+	return EncodeMVT([]MVTLayer{{Name: layer, Features: features}}), nil
+}
+
+// tileProjection projects lng/lat into the web mercator coordinates of one tile
+type tileProjection struct {
+	z, x, y int
+}
+
+func (t tileProjection) project(lng float64, lat float64) [2]float64 {
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	size := MVTExtent * math.Exp2(float64(t.z))
+	rad := helpers.Degs2Rads(lat)
+	px := (lng + 180) / 360 * size
+	py := (1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2 * size
+
+	return [2]float64{px - float64(t.x*MVTExtent), py - float64(t.y*MVTExtent)}
+}
+
+// worldShifts longitude offsets that bring a geometry spilling past the antimeridian back onto the map
+func worldShifts(parts [][][]float64) []float64 {
+	west, east := math.Inf(1), math.Inf(-1)
+	for _, part := range parts {
+		for _, p := range part {
+			west, east = math.Min(west, p[0]), math.Max(east, p[0])
+		}
+	}
+	shifts := []float64{0}
+	if east > 180 {
+		shifts = append(shifts, -360)
+	}
+	if west < -180 {
+		shifts = append(shifts, 360)
+	}
+	return shifts
+}
+
+// feature projects, clips and simplifies a geojson feature into the tile, false when nothing of it is left
+func (t tileProjection) feature(f GeoJSONFeature) (MVTFeature, bool) {
+	var geomType int
+	var parts [][][]float64
+	switch c := f.Geometry.Coordinates.(type) {
+	case []float64:
+		geomType, parts = MVTPoint, [][][]float64{{c}}
+	case [][][]float64:
+		geomType, parts = MVTPolygon, c
+		if f.Geometry.Type == "MultiLineString" {
+			geomType = MVTLineString
+		}
+	case [][][][]float64:
+		geomType = MVTPolygon
+		for _, polygon := range c {
+			parts = append(parts, polygon...)
+		}
+	default:
+		return MVTFeature{}, false
+	}
+
+	geometry := make([][][2]int, 0)
+	for _, shift := range worldShifts(parts) {
+		for _, part := range parts {
+			projected := make([][2]float64, 0)
+			for _, p := range part {
+				projected = append(projected, t.project(p[0]+shift, p[1]))
+			}
+			switch geomType {
+			case MVTPoint:
+				for _, p := range clipPoints(projected) {
+					geometry = append(geometry, [][2]int{roundTilePoint(p)})
+				}
+			case MVTLineString:
+				for _, line := range clipLine(projected) {
+					if points := roundTilePoints(simplifyLine(line, tileSimplifyTolerance)); len(points) >= 2 {
+						geometry = append(geometry, points)
+					}
+				}
+			case MVTPolygon:
+				ring := clipRing(projected)
+				if len(ring) < 3 {
+					continue
+				}
+				points := roundTilePoints(simplifyLine(ring, tileSimplifyTolerance))
+				if len(points) > 1 && points[0] == points[len(points)-1] {
+					points = points[:len(points)-1]
+				}
+				// catseye polygons have no holes, so every ring is written as an exterior ring
+				if len(points) >= 3 && ringArea(points) != 0 {
+					geometry = append(geometry, windRing(points))
+				}
+			}
+		}
+	}
+	if len(geometry) == 0 {
+		return MVTFeature{}, false
+	}
+
+	return MVTFeature{Type: geomType, Geometry: geometry, Properties: f.Properties}, true
+}
+
+// inTile reports whether a point lies within the buffered tile
+func inTile(p [2]float64) bool {
+	return p[0] >= -tileBuffer && p[0] <= MVTExtent+tileBuffer && p[1] >= -tileBuffer && p[1] <= MVTExtent+tileBuffer
+}
+
+func clipPoints(points [][2]float64) [][2]float64 {
+	kept := make([][2]float64, 0)
+	for _, p := range points {
+		if inTile(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// clipLine clips a line string to the buffered tile with Liang-Barsky, starting a new line wherever it leaves the tile
+func clipLine(points [][2]float64) [][][2]float64 {
+	lines := make([][][2]float64, 0)
+	current := make([][2]float64, 0)
+	for i := 1; i < len(points); i++ {
+		a, b, ok := clipSegment(points[i-1], points[i])
+		if !ok {
+			if len(current) > 0 {
+				lines = append(lines, current)
+				current = make([][2]float64, 0)
+			}
+			continue
+		}
+		if len(current) == 0 {
+			current = append(current, a)
+		}
+		current = append(current, b)
+		if b != points[i] {
+			lines = append(lines, current)
+			current = make([][2]float64, 0)
+		}
+	}
+	if len(current) > 1 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func clipSegment(a [2]float64, b [2]float64) ([2]float64, [2]float64, bool) {
+	min, max := float64(-tileBuffer), float64(MVTExtent+tileBuffer)
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t0, t1 := 0.0, 1.0
+	for _, edge := range [][2]float64{{-dx, a[0] - min}, {dx, max - a[0]}, {-dy, a[1] - min}, {dy, max - a[1]}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			t0 = math.Max(t0, r)
+		} else {
+			t1 = math.Min(t1, r)
+		}
+		if t0 > t1 {
+			return a, b, false
+		}
+	}
+	clippedA := a
+	if t0 > 0 {
+		clippedA = [2]float64{a[0] + t0*dx, a[1] + t0*dy}
+	}
+	clippedB := b
+	if t1 < 1 {
+		clippedB = [2]float64{a[0] + t1*dx, a[1] + t1*dy}
+	}
+	return clippedA, clippedB, true
+}
+
+// clipRing clips a closed ring to the buffered tile with Sutherland-Hodgman, returning an open ring
+func clipRing(ring [][2]float64) [][2]float64 {
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	min, max := float64(-tileBuffer), float64(MVTExtent+tileBuffer)
+	edges := []struct {
+		inside func([2]float64) bool
+		cross  func([2]float64, [2]float64) [2]float64
+	}{
+		{func(p [2]float64) bool { return p[0] >= min }, func(a, b [2]float64) [2]float64 { return crossX(a, b, min) }},
+		{func(p [2]float64) bool { return p[0] <= max }, func(a, b [2]float64) [2]float64 { return crossX(a, b, max) }},
+		{func(p [2]float64) bool { return p[1] >= min }, func(a, b [2]float64) [2]float64 { return crossY(a, b, min) }},
+		{func(p [2]float64) bool { return p[1] <= max }, func(a, b [2]float64) [2]float64 { return crossY(a, b, max) }},
+	}
+	for _, edge := range edges {
+		if len(ring) == 0 {
+			break
+		}
+		clipped := make([][2]float64, 0)
+		prev := ring[len(ring)-1]
+		for _, p := range ring {
+			if edge.inside(p) {
+				if !edge.inside(prev) {
+					clipped = append(clipped, edge.cross(prev, p))
+				}
+				clipped = append(clipped, p)
+			} else if edge.inside(prev) {
+				clipped = append(clipped, edge.cross(prev, p))
+			}
+			prev = p
+		}
+		ring = clipped
+	}
+	return ring
+}
+
+func crossX(a [2]float64, b [2]float64, x float64) [2]float64 {
+	return [2]float64{x, a[1] + (x-a[0])*(b[1]-a[1])/(b[0]-a[0])}
+}
+
+func crossY(a [2]float64, b [2]float64, y float64) [2]float64 {
+	return [2]float64{a[0] + (y-a[1])*(b[0]-a[0])/(b[1]-a[1]), y}
+}
+
+// simplifyLine removes vertices closer than tolerance to the line through their neighbours with Douglas-Peucker
+func simplifyLine(points [][2]float64, tolerance float64) [][2]float64 {
+	if len(points) < 3 {
+		return points
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	var simplify func(first int, last int)
+	simplify = func(first int, last int) {
+		farthest, distance := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(points[i], points[first], points[last]); d > distance {
+				farthest, distance = i, d
+			}
+		}
+		if farthest >= 0 {
+			keep[farthest] = true
+			simplify(first, farthest)
+			simplify(farthest, last)
+		}
+	}
+	simplify(0, len(points)-1)
+
+	simplified := make([][2]float64, 0)
+	for i, p := range points {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+func segmentDistance(p [2]float64, a [2]float64, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	t := math.Max(0, math.Min(1, ((p[0]-a[0])*dx+(p[1]-a[1])*dy)/(dx*dx+dy*dy)))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+func roundTilePoint(p [2]float64) [2]int {
+	return [2]int{int(math.Round(p[0])), int(math.Round(p[1]))}
+}
+
+// roundTilePoints rounds to integer tile coordinates and drops vertices that round onto their predecessor
+func roundTilePoints(points [][2]float64) [][2]int {
+	rounded := make([][2]int, 0)
+	for _, p := range points {
+		r := roundTilePoint(p)
+		if len(rounded) > 0 && rounded[len(rounded)-1] == r {
+			continue
+		}
+		rounded = append(rounded, r)
+	}
+	return rounded
+}
+
+// ringArea twice the signed area of a ring by the surveyor's formula, positive when clockwise in tile coordinates
+func ringArea(ring [][2]int) int {
+	area := 0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area
+}
+
+// windRing orders a ring so it has the positive area the vector tile spec requires of exterior rings
+func windRing(ring [][2]int) [][2]int {
+	if ringArea(ring) > 0 {
+		return ring
+	}
+	reversed := make([][2]int, len(ring))
+	for i, p := range ring {
+		reversed[len(ring)-1-i] = p
+	}
+	return reversed
+}
+
+// sortedKeys property names in a stable order so tiles encode the same way every time
+func sortedKeys(properties map[string]interface{}) []string {
+	keys := make([]string, 0)
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
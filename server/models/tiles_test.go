@@ -0,0 +1,177 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// tileRingArea signed area of an open ring in tile coordinates, positive when clockwise on screen
+func tileRingArea(ring [][2]float64) float64 {
+	area := 0.0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area = area + ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+func TestClipLine(t *testing.T) {
+	// the buffered tile runs from -64 to 4160 on both axes
+	tests := []struct {
+		name   string
+		points [][2]float64
+		want   [][][2]float64
+	}{
+		{"inside", [][2]float64{{100, 100}, {200, 300}}, [][][2]float64{{{100, 100}, {200, 300}}}},
+		{"crossing the east edge", [][2]float64{{4000, 100}, {4300, 100}}, [][][2]float64{{{4000, 100}, {4160, 100}}}},
+		{"crossing the west edge", [][2]float64{{-200, 200}, {100, 500}}, [][][2]float64{{{-64, 336}, {100, 500}}}},
+		{"through the corner", [][2]float64{{-164, -164}, {36, 36}}, [][][2]float64{{{-64, -64}, {36, 36}}}},
+		{"leaving and coming back", [][2]float64{{100, 100}, {5000, 100}, {5000, 200}, {100, 200}},
+			[][][2]float64{{{100, 100}, {4160, 100}}, {{4160, 200}, {100, 200}}}},
+		{"across the whole tile", [][2]float64{{-1000, 2000}, {5000, 2000}}, [][][2]float64{{{-64, 2000}, {4160, 2000}}}},
+		{"outside", [][2]float64{{5000, 100}, {5000, 4000}}, [][][2]float64{}},
+	}
+
+	for _, tt := range tests {
+		if got := clipLine(tt.points); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: clipLine = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClipRing(t *testing.T) {
+	tests := []struct {
+		name  string
+		ring  [][2]float64
+		area  float64
+		west  float64
+		east  float64
+		north float64
+		south float64
+	}{
+		{"inside", [][2]float64{{100, 100}, {200, 100}, {200, 200}, {100, 200}, {100, 100}}, 10000, 100, 200, 100, 200},
+		{"over the north west corner", [][2]float64{{-200, -200}, {200, -200}, {200, 200}, {-200, 200}}, 264 * 264, -64, 200, -64, 200},
+		{"over the east edge", [][2]float64{{4000, 1000}, {4400, 1000}, {4400, 2000}, {4000, 2000}}, 160 * 1000, 4000, 4160, 1000, 2000},
+		{"covering the tile", [][2]float64{{-1000, -1000}, {9000, -1000}, {9000, 9000}, {-1000, 9000}}, 4224 * 4224, -64, 4160, -64, 4160},
+		{"outside", [][2]float64{{5000, 100}, {6000, 100}, {6000, 200}, {5000, 200}}, 0, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		ring := clipRing(tt.ring)
+		if tt.area == 0 {
+			if len(ring) != 0 {
+				t.Errorf("%s: clipRing = %v, want nothing", tt.name, ring)
+			}
+			continue
+		}
+		if ring[0] == ring[len(ring)-1] {
+			t.Errorf("%s: clipRing returned a closed ring %v", tt.name, ring)
+		}
+		if area := tileRingArea(ring); math.Abs(area-tt.area) > 1e-6 {
+			t.Errorf("%s: clipped area %g, want %g", tt.name, area, tt.area)
+		}
+		west, east, north, south := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+		for _, p := range ring {
+			west, east = math.Min(west, p[0]), math.Max(east, p[0])
+			north, south = math.Min(north, p[1]), math.Max(south, p[1])
+		}
+		if west != tt.west || east != tt.east || north != tt.north || south != tt.south {
+			t.Errorf("%s: clipped ring spans x %g to %g, y %g to %g, want x %g to %g, y %g to %g",
+				tt.name, west, east, north, south, tt.west, tt.east, tt.north, tt.south)
+		}
+	}
+}
+
+func TestSimplifyLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		points [][2]float64
+		want   [][2]float64
+	}{
+		{"two points", [][2]float64{{0, 0}, {10, 0}}, [][2]float64{{0, 0}, {10, 0}}},
+		{"nearly straight", [][2]float64{{0, 0}, {10, 1}, {20, -1}, {30, 0}}, [][2]float64{{0, 0}, {30, 0}}},
+		{"spike is kept", [][2]float64{{0, 0}, {6, 8}, {15, 20}, {24, 8}, {30, 0}}, [][2]float64{{0, 0}, {15, 20}, {30, 0}}},
+		{"right angle", [][2]float64{{0, 0}, {50, 0}, {50, 2}, {50, 50}}, [][2]float64{{0, 0}, {50, 0}, {50, 50}}},
+		// the distance to a segment from a point to itself is measured to the point, so a closed ring keeps its corners
+		{"closed loop", [][2]float64{{0, 0}, {100, 0}, {100, 100}, {0, 0}}, [][2]float64{{0, 0}, {100, 0}, {100, 100}, {0, 0}}},
+	}
+
+	for _, tt := range tests {
+		if got := simplifyLine(tt.points, tileSimplifyTolerance); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: simplifyLine = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWorldShifts(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts [][][]float64
+		want  []float64
+	}{
+		{"on the map", [][][]float64{{{-170, 0}, {170, 10}}}, []float64{0}},
+		{"past 180", [][][]float64{{{170, 0}, {190, 10}}}, []float64{0, -360}},
+		{"past -180", [][][]float64{{{-190, 0}, {-170, 10}}}, []float64{0, 360}},
+		{"past both", [][][]float64{{{-190, 0}}, {{190, 0}}}, []float64{0, -360, 360}},
+	}
+
+	for _, tt := range tests {
+		if got := worldShifts(tt.parts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: worldShifts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTileFeatureAcrossAntimeridian(t *testing.T) {
+	// zoom 1 splits the world at the prime meridian, so a geometry from 170 to 190 east lies on the east edge of
+	// tile 1/1/0 and is shifted a world west onto the west edge of tile 1/0/0
+	line := GeoJSONFeature{Geometry: GeoJSONGeometry{"MultiLineString", [][][]float64{{{170, 10}, {190, 10}}}}}
+	polygon := GeoJSONFeature{Geometry: GeoJSONGeometry{"Polygon", [][][]float64{{{170, 0}, {190, 0}, {190, 10}, {170, 10}, {170, 0}}}}}
+	tests := []struct {
+		name     string
+		feature  GeoJSONFeature
+		x        int
+		geomType int
+		west     int
+		east     int
+	}{
+		{"line in the eastern tile", line, 1, MVTLineString, 3868, 4160},
+		{"line in the western tile", line, 0, MVTLineString, -64, 228},
+		{"polygon in the eastern tile", polygon, 1, MVTPolygon, 3868, 4160},
+		{"polygon in the western tile", polygon, 0, MVTPolygon, -64, 228},
+	}
+
+	for _, tt := range tests {
+		f, ok := tileProjection{1, tt.x, 0}.feature(tt.feature)
+		if !ok {
+			t.Errorf("%s: nothing left in tile 1/%d/0", tt.name, tt.x)
+			continue
+		}
+		if f.Type != tt.geomType || len(f.Geometry) != 1 {
+			t.Errorf("%s: type %d with %d parts, want type %d with 1 part", tt.name, f.Type, len(f.Geometry), tt.geomType)
+			continue
+		}
+		part := f.Geometry[0]
+		west, east := part[0][0], part[0][0]
+		for _, p := range part {
+			if p[0] < west {
+				west = p[0]
+			}
+			if p[0] > east {
+				east = p[0]
+			}
+		}
+		if west != tt.west || east != tt.east {
+			t.Errorf("%s: part spans x %d to %d, want %d to %d", tt.name, west, east, tt.west, tt.east)
+		}
+		if tt.geomType == MVTPolygon {
+			if len(part) != 4 {
+				t.Errorf("%s: ring has %d vertices, want 4", tt.name, len(part))
+			}
+			if ringArea(part) <= 0 {
+				t.Errorf("%s: ring %v is not wound as an exterior ring", tt.name, part)
+			}
+		}
+	}
+}
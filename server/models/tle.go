@@ -0,0 +1,248 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tleLineLength length of each element line of a tle
+const tleLineLength = 69
+
+// earthMu WGS84 earth gravitational parameter in cubic kilometers per second squared
+const earthMu = 398600.4418
+
+// TLEEpoch parses the epoch year and fractional day of year from the first line of a tle
+func TLEEpoch(line1 string) (time.Time, error) {
+	if len(line1) < 32 {
+		return time.Time{}, fmt.Errorf("tle line 1 is too short to hold an epoch")
+	}
+	year, err := strconv.Atoi(strings.TrimSpace(line1[18:20]))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tle epoch year %q is not a number", line1[18:20])
+	}
+	day, err := strconv.ParseFloat(strings.TrimSpace(line1[20:32]), 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tle epoch day %q is not a number", line1[20:32])
+	}
+
+	// two digit years 57-99 are 1900s, 00-56 are 2000s
+	if year < 57 {
+		year = year + 2000
+	} else {
+		year = year + 1900
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	return start.Add(time.Duration((day - 1) * float64(24*time.Hour))), nil
+}
+
+// TLEMeanAltitude mean altitude in kilometers above the WGS84 equatorial radius of the orbit in a tle,
+// from the semi-major axis implied by the mean motion on line 2
+func TLEMeanAltitude(line2 string) (float64, error) {
+	if len(line2) < 63 {
+		return 0, fmt.Errorf("tle line 2 is too short to hold a mean motion")
+	}
+	revsPerDay, err := strconv.ParseFloat(strings.TrimSpace(line2[52:63]), 64)
+	if err != nil || revsPerDay <= 0 {
+		return 0, fmt.Errorf("tle mean motion %q is not a positive number", line2[52:63])
+	}
+	n := revsPerDay * 2 * math.Pi / 86400
+
+	return math.Cbrt(earthMu/(n*n)) - wgs84A, nil
+}
+
+// TLEChecksum computes the modulo 10 checksum of the first 68 characters of a tle line,
+// where digits count their value, minus signs count 1 and everything else counts 0
+func TLEChecksum(line string) int {
+	sum := 0
+	for i := 0; i < len(line) && i < tleLineLength-1; i++ {
+		switch c := line[i]; {
+		case c >= '0' && c <= '9':
+			sum = sum + int(c-'0')
+		case c == '-':
+			sum = sum + 1
+		}
+	}
+
+	return sum % 10
+}
+
+// tleField numeric field of tle line 2 with its zero based, end exclusive columns and allowed values
+type tleField struct {
+	name    string
+	start   int
+	end     int
+	min     float64
+	max     float64
+	implied bool
+}
+
+// tleLine2Fields line 2 fields range checked by ValidateTLE
+var tleLine2Fields = []tleField{
+	{name: "inclination", start: 8, end: 16, min: 0, max: 180},
+	{name: "raan", start: 17, end: 25, min: 0, max: 360},
+	{name: "eccentricity", start: 26, end: 33, min: 0, max: 1, implied: true},
+	{name: "argumentOfPerigee", start: 34, end: 42, min: 0, max: 360},
+	{name: "meanAnomaly", start: 43, end: 51, min: 0, max: 360},
+	{name: "meanMotion", start: 52, end: 63, min: 0.05, max: 20},
+}
+
+// ValidateTLE checks the line numbers, lengths, checksums, catalog numbers and field ranges of a tle.
+// line is the line of the source text that line1 was read from and is used to place the issues
+func ValidateTLE(satID string, line1 string, line2 string, line int) []TLEIssue {
+	issues := make([]TLEIssue, 0)
+	fail := func(offset int, field string, format string, a ...interface{}) {
+		issues = append(issues, TLEIssue{
+			Line:        line + offset,
+			SatelliteID: satID,
+			Field:       field,
+			Severity:    "error",
+			Message:     fmt.Sprintf(format, a...),
+		})
+	}
+
+	for i, l := range []string{line1, line2} {
+		if len(l) != tleLineLength {
+			fail(i, "length", "line %d must be %d characters, got %d", i+1, tleLineLength, len(l))
+			continue
+		}
+		if l[0] != byte('1'+i) || l[1] != ' ' {
+			fail(i, "lineNumber", "line %d must start with its line number", i+1)
+		}
+		checksum, err := strconv.Atoi(l[68:69])
+		if err != nil {
+			fail(i, "checksum", "line %d checksum %q is not a digit", i+1, l[68:69])
+		} else if sum := TLEChecksum(l); sum != checksum {
+			fail(i, "checksum", "line %d checksum is %d but the line sums to %d", i+1, checksum, sum)
+		}
+	}
+	// the remaining checks read fixed columns
+	if len(line1) != tleLineLength || len(line2) != tleLineLength {
+		return issues
+	}
+
+	if catalog1, catalog2 := strings.TrimSpace(line1[2:7]), strings.TrimSpace(line2[2:7]); catalog1 != catalog2 {
+		fail(1, "catalogNumber", "line 2 catalog number %s does not match line 1 catalog number %s", catalog2, catalog1)
+	}
+	if classification := line1[7]; classification != 'U' && classification != 'C' && classification != 'S' {
+		fail(0, "classification", "classification %q must be U, C or S", string(classification))
+	}
+	if _, err := TLEEpoch(line1); err != nil {
+		fail(0, "epoch", "%v", err)
+	} else if day, _ := strconv.ParseFloat(strings.TrimSpace(line1[20:32]), 64); day < 1 || day >= 367 {
+		fail(0, "epoch", "epoch day %v must be between 1 and 366", day)
+	}
+
+	for _, f := range tleLine2Fields {
+		raw := strings.TrimSpace(line2[f.start:f.end])
+		if f.implied {
+			// eccentricity is written without its leading decimal point
+			raw = "0." + raw
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			fail(1, f.name, "%s %q is not a number", f.name, line2[f.start:f.end])
+			continue
+		}
+		if v < f.min || v > f.max {
+			fail(1, f.name, "%s %v must be between %v and %v", f.name, v, f.min, f.max)
+		}
+	}
+
+	return issues
+}
+
+// tleName satellite id used as the key of a tle, the last 4 characters of the name line
+func tleName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > 4 {
+		return name[len(name)-4:]
+	}
+
+	return name
+}
+
+// ParseTLESet reads 3 line element set text into a map of tle lines keyed by satellite id.
+// Satellites whose lines fail ValidateTLE are left out, and every problem found, including
+// stray lines and incomplete sets, is listed in the returned report instead of panicking
+func ParseTLESet(text string, source string) (map[string]map[string]string, IngestReport) {
+	report := IngestReport{
+		Source:   source,
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		Accepted: make([]string, 0),
+		Rejected: make([]string, 0),
+		Issues:   make([]TLEIssue, 0),
+	}
+	addIssue := func(line int, satID string, field string, severity string, message string) {
+		report.Issues = append(report.Issues, TLEIssue{
+			Line:        line,
+			SatelliteID: satID,
+			Field:       field,
+			Severity:    severity,
+			Message:     message,
+		})
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r \t")
+	}
+	isElementLine := func(i int, number byte) bool {
+		return i < len(lines) && len(lines[i]) > 1 && lines[i][0] == number && lines[i][1] == ' '
+	}
+
+	tlemap := make(map[string]map[string]string, 0)
+	name, nameLine := "", 0
+	for i := 0; i < len(lines); i++ {
+		switch {
+		case lines[i] == "":
+			continue
+		case isElementLine(i, '1'):
+			satID := tleName(name)
+			if satID == "" && len(lines[i]) >= 7 {
+				satID = strings.TrimSpace(lines[i][2:7])
+				addIssue(i+1, satID, "name", "warning", "tle has no name line, using its catalog number as the satellite id")
+			}
+			name = ""
+			if !isElementLine(i+1, '2') {
+				addIssue(i+1, satID, "lineNumber", "error", "line 1 is not followed by a line 2")
+				report.Rejected = append(report.Rejected, satID)
+				continue
+			}
+
+			issues := ValidateTLE(satID, lines[i], lines[i+1], i+1)
+			report.Issues = append(report.Issues, issues...)
+			if len(issues) > 0 {
+				report.Rejected = append(report.Rejected, satID)
+			} else {
+				if _, ok := tlemap[satID]; ok {
+					addIssue(i+1, satID, "name", "warning", fmt.Sprintf("satellite %s appears more than once, keeping the last tle", satID))
+				} else {
+					report.Accepted = append(report.Accepted, satID)
+				}
+				tlemap[satID] = map[string]string{
+					"firstline":  lines[i],
+					"secondline": lines[i+1],
+				}
+			}
+			i++
+		case isElementLine(i, '2'):
+			addIssue(i+1, tleName(name), "lineNumber", "error", "line 2 is not preceded by a line 1")
+			name = ""
+		default:
+			// a title line before the first name line is allowed and reported
+			if name != "" {
+				addIssue(nameLine, "", "name", "warning", fmt.Sprintf("ignoring %q, it is not followed by tle lines", name))
+			}
+			name, nameLine = lines[i], i+1
+		}
+	}
+	if name != "" {
+		addIssue(nameLine, "", "name", "warning", fmt.Sprintf("ignoring %q, it is not followed by tle lines", name))
+	}
+
+	return tlemap, report
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	issLine1 = "1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927"
+	issLine2 = "2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537"
+)
+
+func TestTLEChecksum(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want int
+	}{
+		{"line 1 with minus signs", issLine1, 7},
+		{"line 2", issLine2, 7},
+		{"empty line", "", 0},
+		{"letters and spaces count nothing", "1 A B C D E U", 1},
+		{"minus signs count one", "---", 3},
+		{"plus signs and points count nothing", "+.+.", 0},
+		{"sum wraps modulo 10", "99", 8},
+		{"checksum column is ignored", strings.Repeat("0", 68) + "9", 0},
+		{"characters past the checksum column are ignored", strings.Repeat("0", 67) + "1" + "99", 1},
+		{"minus sign in the last summed column", strings.Repeat(" ", 67) + "-", 1},
+	}
+
+	for _, tt := range tests {
+		if got := TLEChecksum(tt.line); got != tt.want {
+			t.Errorf("%s: TLEChecksum(%q) = %d, want %d", tt.name, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTLEChecksumDetectsSignChange(t *testing.T) {
+	// a dropped minus sign changes the sum by one, so a valid line fails its own checksum
+	flipped := strings.Replace(issLine1, "-.00002182", " .00002182", 1)
+	if TLEChecksum(flipped) == TLEChecksum(issLine1) {
+		t.Errorf("TLEChecksum did not change when a minus sign was removed")
+	}
+	if issues := ValidateTLE("M001", flipped, issLine2, 1); len(issues) != 1 || issues[0].Field != "checksum" {
+		t.Errorf("ValidateTLE of a line with a dropped minus sign = %+v, want one checksum issue", issues)
+	}
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// TLEStaleAfter age of a satellite's tle epoch after which its elements are flagged as stale
+var TLEStaleAfter = 72 * time.Hour
+
+// TLEAge epoch of the tle a satellite is propagated with at a time and how old it is then
+type TLEAge struct {
+	SatelliteID string
+	Epoch       time.Time
+	AgeHours    float64
+}
+
+// GetTLEAge reads the epoch of the tle a satellite is propagated with at the given time, the archived tle closest to
+// it as InitSatelliteOrbits picks, or the FLEET tle when the satellite has no history, and its age at that time
+func GetTLEAge(id string, t time.Time) (TLEAge, error) {
+	satstate, ok := GetSatelliteState(id)
+	if !ok {
+		return TLEAge{}, fmt.Errorf("satellite %s not found", id)
+	}
+	line1 := satstate.TLELine1
+	if archived, ok := GetTLEAt(id, t); ok {
+		line1 = archived.TLELine1
+	}
+	epoch, err := TLEEpoch(line1)
+	if err != nil {
+		return TLEAge{}, err
+	}
+
+	return TLEAge{
+		SatelliteID: id,
+		Epoch:       epoch,
+		AgeHours:    t.Sub(epoch).Hours(),
+	}, nil
+}
+
+// TLEStalenessSummary fleet wide summary of tle epoch ages
+type TLEStalenessSummary struct {
+	ThresholdHours  float64  `json:"thresholdHours"`
+	Satellites      int      `json:"satellites"`
+	StaleCount      int      `json:"staleCount"`
+	StaleSatellites []string `json:"staleSatellites"`
+	OldestEpoch     string   `json:"oldestEpoch"`
+	NewestEpoch     string   `json:"newestEpoch"`
+	MaxAgeHours     float64  `json:"maxAgeHours"`
+}
+
+// TLEStalenessSummaryType graphql object for fleet tle staleness summaries
+var TLEStalenessSummaryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TLEStalenessSummary",
+	Fields: graphql.Fields{
+		"thresholdHours": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "tle age in hours above which a satellite is stale",
+		},
+		"satellites": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "satellites with a readable tle epoch",
+		},
+		"staleCount": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"staleSatellites": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "stale satellites, oldest tle first",
+		},
+		"oldestEpoch": &graphql.Field{
+			Type: graphql.String,
+		},
+		"newestEpoch": &graphql.Field{
+			Type: graphql.String,
+		},
+		"maxAgeHours": &graphql.Field{
+			Type: graphql.Float,
+		},
+	},
+})
+
+// BuildTLEStalenessSummary summarizes the tle ages of satellites against a staleness threshold
+func BuildTLEStalenessSummary(ages []TLEAge, thresholdHours float64) TLEStalenessSummary {
+	summary := TLEStalenessSummary{
+		ThresholdHours:  thresholdHours,
+		Satellites:      len(ages),
+		StaleSatellites: make([]string, 0),
+	}
+	if len(ages) == 0 {
+		return summary
+	}
+
+	sort.Slice(ages, func(i, j int) bool {
+		return ages[i].AgeHours > ages[j].AgeHours
+	})
+	for _, age := range ages {
+		if age.AgeHours > summary.ThresholdHours {
+			summary.StaleSatellites = append(summary.StaleSatellites, age.SatelliteID)
+		}
+	}
+	summary.StaleCount = len(summary.StaleSatellites)
+	summary.OldestEpoch = ages[0].Epoch.Format(time.RFC3339)
+	summary.NewestEpoch = ages[len(ages)-1].Epoch.Format(time.RFC3339)
+	summary.MaxAgeHours = ages[0].AgeHours
+
+	return summary
+}
+
+// resolveStaleness builds the staleness summary of satellite features at the times of their positions with an optional
+// staleAfterHours argument
+func resolveStaleness(features []SatelliteFeature, args map[string]interface{}) (interface{}, error) {
+	thresholdHours := TLEStaleAfter.Hours()
+	if hours, ok := args["staleAfterHours"].(float64); ok {
+		if hours <= 0 {
+			return nil, fmt.Errorf("staleAfterHours must be positive")
+		}
+		thresholdHours = hours
+	}
+
+	ages := make([]TLEAge, 0)
+	for _, s := range features {
+		age, err := GetTLEAge(s.Properties.ID, s.propagatedAt())
+		if err != nil {
+			continue
+		}
+		ages = append(ages, age)
+	}
+
+	return BuildTLEStalenessSummary(ages, thresholdHours), nil
+}
+
+// satelliteTLEAge resolves the tle age of the satellite feature a field is on at the time of its position
+func satelliteTLEAge(params graphql.ResolveParams) (TLEAge, error) {
+	s := params.Source.(SatelliteFeature)
+
+	return GetTLEAge(s.Properties.ID, s.propagatedAt())
+}
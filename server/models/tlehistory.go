@@ -0,0 +1,196 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/boltdb/bolt"
+	"github.com/graphql-go/graphql"
+)
+
+// tleEpochKeyFormat fixed width utc epoch used as the TLEHISTORY key so keys sort by epoch
+const tleEpochKeyFormat = "2006-01-02T15:04:05.000000Z"
+
+// ArchivedTLE tle kept in a satellite's TLEHISTORY bucket
+type ArchivedTLE struct {
+	SatelliteID string `json:"satelliteID"`
+	Epoch       string `json:"epoch"`
+	TLELine1    string `json:"tleLine1"`
+	TLELine2    string `json:"tleLine2"`
+	ArchivedAt  string `json:"archivedAt"`
+}
+
+// ArchivedTLEType graphql object for archived tles
+var ArchivedTLEType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ArchivedTLE",
+	Fields: graphql.Fields{
+		"satelliteId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"epoch": &graphql.Field{
+			Type: graphql.String,
+		},
+		"tleLine1": &graphql.Field{
+			Type: graphql.String,
+		},
+		"tleLine2": &graphql.Field{
+			Type: graphql.String,
+		},
+		"archivedAt": &graphql.Field{
+			Type:        graphql.String,
+			Description: "RFC3339 time the tle was first ingested",
+		},
+	},
+})
+
+// archiveTLE adds a tle to the satellite's TLEHISTORY bucket under its epoch. A tle with an epoch that is
+// already archived replaces its lines but keeps the time the epoch was first archived
+func archiveTLE(tx *bolt.Tx, satID string, line1 string, line2 string) error {
+	epoch, err := TLEEpoch(line1)
+	if err != nil {
+		return err
+	}
+	archived := ArchivedTLE{
+		SatelliteID: satID,
+		Epoch:       epoch.Format(time.RFC3339Nano),
+		TLELine1:    line1,
+		TLELine2:    line2,
+		ArchivedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	b, err := tx.Bucket([]byte("DB")).Bucket([]byte("TLEHISTORY")).CreateBucketIfNotExists([]byte(satID))
+	if err != nil {
+		return fmt.Errorf("could not create tle history bucket for %s: %v", satID, err)
+	}
+	key := []byte(epoch.Format(tleEpochKeyFormat))
+	if existing := b.Get(key); existing != nil {
+		var previous ArchivedTLE
+		json.Unmarshal(existing, &previous)
+		archived.ArchivedAt = previous.ArchivedAt
+	}
+
+	archivedBytes, err := json.MarshalIndent(archived, "", "\t")
+	if err != nil {
+		return err
+	}
+	archivedBytes = bytes.Replace(archivedBytes, []byte("\\u0026"), []byte("&"), -1)
+	return b.Put(key, archivedBytes)
+}
+
+// GetTLEAt returns the satellite's archived tle whose epoch is closest to the given time
+func GetTLEAt(satID string, t time.Time) (ArchivedTLE, bool) {
+	var archived ArchivedTLE
+	found := false
+	err := DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("TLEHISTORY")).Bucket([]byte(satID))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		target := t.UTC().Format(tleEpochKeyFormat)
+
+		// the closest epoch is the first one at or after t, or the one just before it
+		k, v := c.Seek([]byte(target))
+		if k == nil {
+			k, v = c.Last()
+		} else if string(k) != target {
+			pk, pv := c.Prev()
+			if pk != nil {
+				after, _ := time.Parse(tleEpochKeyFormat, string(k))
+				before, _ := time.Parse(tleEpochKeyFormat, string(pk))
+				if t.Sub(before) <= after.Sub(t) {
+					k, v = pk, pv
+				}
+			}
+		}
+		if k != nil {
+			found = true
+			json.Unmarshal(v, &archived)
+		}
+		return nil
+	})
+	helpers.PanicErrors(err)
+
+	return archived, found
+}
+
+// GetTLEHistory returns every archived tle of a satellite, oldest epoch first
+func GetTLEHistory(satID string) []ArchivedTLE {
+	history := make([]ArchivedTLE, 0)
+	err := DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("TLEHISTORY")).Bucket([]byte(satID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var archived ArchivedTLE
+			json.Unmarshal(v, &archived)
+			history = append(history, archived)
+			return nil
+		})
+	})
+	helpers.PanicErrors(err)
+
+	return history
+}
+
+// SatelliteOrbitAt initializes a satellite's orbit with sgp4 from the archived tle closest to the given time,
+// falling back to the satellite's FLEET tle when it has no history
+func SatelliteOrbitAt(satID string, t time.Time) (Orbit, bool) {
+	roster := GetRoster()
+	if archived, ok := GetTLEAt(satID, t); ok {
+		return NewOrbit(satID, archived.TLELine1, archived.TLELine2, roster), true
+	}
+	satstate, ok := GetSatelliteState(satID)
+	if !ok {
+		return Orbit{}, false
+	}
+
+	return NewOrbit(satID, satstate.TLELine1, satstate.TLELine2, roster), true
+}
+
+// SatelliteOrbitOver initializes a satellite's orbit for propagating across a window. Every sample is propagated from
+// the archived tle whose epoch is closest to it, as SatelliteOrbitAt picks one, so long windows switch tles as they
+// cross newer epochs. Satellites with no history use their FLEET tle
+func SatelliteOrbitOver(satID string, start time.Time, end time.Time) (Orbit, bool) {
+	history := GetTLEHistory(satID)
+	if len(history) == 0 {
+		return SatelliteOrbitAt(satID, start)
+	}
+	epochs := make([]time.Time, len(history))
+	for i, archived := range history {
+		epochs[i], _ = time.Parse(time.RFC3339Nano, archived.Epoch)
+	}
+
+	roster := GetRoster()
+	var orbit Orbit
+	for i, archived := range history {
+		// the tle is the closest one from halfway to the previous epoch to halfway to the next
+		var from, until time.Time
+		if i > 0 {
+			from = windowMidpoint(epochs[i-1], epochs[i])
+		}
+		if i < len(history)-1 {
+			until = windowMidpoint(epochs[i], epochs[i+1])
+		}
+		if (!until.IsZero() && until.Before(start)) || (!from.IsZero() && !from.Before(end)) {
+			continue
+		}
+
+		o := NewOrbit(satID, archived.TLELine1, archived.TLELine2, roster)
+		if len(orbit.history) == 0 {
+			orbit = o
+		}
+		orbit.history = append(orbit.history, tleOrbit{until, o.SGP4})
+	}
+
+	return orbit, true
+}
+
+// windowMidpoint time halfway between two times
+func windowMidpoint(start time.Time, end time.Time) time.Time {
+	return start.Add(end.Sub(start) / 2)
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/graphql-go/graphql"
+)
+
+// TLEChange result of replacing a satellite's tle in the FLEET bucket
+type TLEChange struct {
+	SatelliteID     string  `json:"satelliteID"`
+	PreviousEpoch   string  `json:"previousEpoch"`
+	Epoch           string  `json:"epoch"`
+	EpochShiftHours float64 `json:"epochShiftHours"`
+}
+
+// TLEChangeType graphql object for tle upload results per satellite
+var TLEChangeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TLEChange",
+	Fields: graphql.Fields{
+		"satelliteId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"previousEpoch": &graphql.Field{
+			Type: graphql.String,
+		},
+		"epoch": &graphql.Field{
+			Type: graphql.String,
+		},
+		"epochShiftHours": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "hours the epoch moved, negative when the new elements are older",
+		},
+	},
+})
+
+// TLEUploadResult summary of a tle upload
+type TLEUploadResult struct {
+	Changed []TLEChange `json:"changed"`
+	Ignored []string    `json:"ignored"`
+	Issues  []TLEIssue  `json:"issues"`
+}
+
+// TLEUploadResultType graphql object for tle upload results
+var TLEUploadResultType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TLEUploadResult",
+	Fields: graphql.Fields{
+		"changed": &graphql.Field{
+			Type:        graphql.NewList(TLEChangeType),
+			Description: "satellites whose tle was replaced",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(TLEUploadResult)
+
+				return s.Changed, nil
+			},
+		},
+		"ignored": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "satellites in the upload that are not in the FLEET bucket",
+		},
+		"issues": &graphql.Field{
+			Type:        graphql.NewList(TLEIssueType),
+			Description: "problems found in the upload, satellites with errors are left unchanged",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(TLEUploadResult)
+
+				return s.Issues, nil
+			},
+		},
+	},
+})
+
+// UploadTLE parses a 3 line element set or CCSDS OMM, replaces the tle lines of every matching satellite in the FLEET bucket
+// and swaps the propagation loop over to the new elements. Every epoch is checked before anything is written, and the
+// satellites are written together, so a failed upload leaves the fleet as it was
+func UploadTLE(text string) (TLEUploadResult, error) {
+	tlemap, report := ParseElements(text, "upload")
+	RecordIngestReport(report)
+	satStates := GetSatelliteStates()
+
+	result := TLEUploadResult{
+		Changed: make([]TLEChange, 0),
+		Ignored: make([]string, 0),
+		Issues:  report.Issues,
+	}
+	updated := make(map[string]SatelliteState, 0)
+	for sat, tle := range tlemap {
+		satstate, ok := satStates[sat]
+		if !ok {
+			result.Ignored = append(result.Ignored, sat)
+			continue
+		}
+
+		change := TLEChange{SatelliteID: sat}
+		previous, perr := TLEEpoch(satstate.TLELine1)
+		if perr == nil {
+			change.PreviousEpoch = previous.Format(time.RFC3339)
+		}
+		epoch, err := TLEEpoch(tle["firstline"])
+		if err != nil {
+			return TLEUploadResult{}, fmt.Errorf("satellite %s: %v", sat, err)
+		}
+		change.Epoch = epoch.Format(time.RFC3339)
+		if perr == nil {
+			change.EpochShiftHours = epoch.Sub(previous).Hours()
+		}
+
+		satstate.TLELine1 = tle["firstline"]
+		satstate.TLELine2 = tle["secondline"]
+		updated[sat] = satstate
+		result.Changed = append(result.Changed, change)
+	}
+
+	if len(updated) > 0 {
+		err := DB.Update(func(tx *bolt.Tx) error {
+			for sat, satstate := range updated {
+				if err := putSatelliteState(tx, sat, satstate); err != nil {
+					return err
+				}
+			}
+			// catseyes are drawn for the fleet's mean altitude, so they follow the new elements
+			return fillCatseyes(tx)
+		})
+		if err != nil {
+			return TLEUploadResult{}, err
+		}
+		LiveSatellites.Store(InitSatelliteOrbits(GetSatelliteStates(), time.Now()))
+	}
+
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return result.Changed[i].SatelliteID < result.Changed[j].SatelliteID
+	})
+	sort.Strings(result.Ignored)
+
+	return result, nil
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alexmspina/worldmap/server/helpers"
+	"github.com/graphql-go/graphql"
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+// WGS84 ellipsoid constants in kilometers, matching the units returned by satellite.Propagate
+const (
+	wgs84A            = 6378.137
+	wgs84F            = 1 / 298.257223563
+	wgs84E2           = wgs84F * (2 - wgs84F)
+	earthRotationRate = 7.292115e-5
+)
+
+// LookAngles topocentric view of a satellite from a point on the ground
+type LookAngles struct {
+	TargetID    string  `json:"targetID"`
+	SatelliteID string  `json:"satelliteID"`
+	Time        string  `json:"time"`
+	Azimuth     float64 `json:"azimuth"`
+	Elevation   float64 `json:"elevation"`
+	Range       float64 `json:"range"`
+	RangeRate   float64 `json:"rangeRate"`
+}
+
+// LookAnglesType graphql object for topocentric look angles
+var LookAnglesType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LookAngles",
+	Fields: graphql.Fields{
+		"targetId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"satelliteId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"time": &graphql.Field{
+			Type: graphql.String,
+		},
+		"azimuth": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "degrees clockwise from true north",
+		},
+		"elevation": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "degrees above the local horizon",
+		},
+		"range": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "slant range in kilometers",
+		},
+		"rangeRate": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "slant range rate in kilometers per second, positive when receding",
+		},
+	},
+})
+
+// Observer geodetic location on the WGS84 ellipsoid
+type Observer struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// TargetObserver converts a target feature into an observer, reading its altitude in meters
+func TargetObserver(t TargetFeature) Observer {
+	return Observer{
+		Latitude:  t.Geometry.Coordinates[1],
+		Longitude: t.Geometry.Coordinates[0],
+		Altitude:  helpers.ConvertStringToFloat64(t.Properties.Altitude) / 1000.0,
+	}
+}
+
+// GeodeticToECEF converts a geodetic observer in degrees and kilometers to earth-fixed coordinates in kilometers
+func GeodeticToECEF(o Observer) satellite.Vector3 {
+	lat := helpers.Degs2Rads(o.Latitude)
+	lng := helpers.Degs2Rads(o.Longitude)
+	n := wgs84A / math.Sqrt(1-wgs84E2*math.Sin(lat)*math.Sin(lat))
+
+	return satellite.Vector3{
+		X: (n + o.Altitude) * math.Cos(lat) * math.Cos(lng),
+		Y: (n + o.Altitude) * math.Cos(lat) * math.Sin(lng),
+		Z: (n*(1-wgs84E2) + o.Altitude) * math.Sin(lat),
+	}
+}
+
+// RotateECIToECEF rotates an eci vector into the earth-fixed frame using greenwich sidereal time
+func RotateECIToECEF(v satellite.Vector3, gmst float64) satellite.Vector3 {
+	return satellite.Vector3{
+		X: v.X*math.Cos(gmst) + v.Y*math.Sin(gmst),
+		Y: -v.X*math.Sin(gmst) + v.Y*math.Cos(gmst),
+		Z: v.Z,
+	}
+}
+
+// RotateECIVelocityToECEF rotates an eci velocity into the earth-fixed frame, removing the earth's rotation at the earth-fixed position
+func RotateECIVelocityToECEF(vel satellite.Vector3, ecefPos satellite.Vector3, gmst float64) satellite.Vector3 {
+	v := RotateECIToECEF(vel, gmst)
+	return satellite.Vector3{
+		X: v.X + earthRotationRate*ecefPos.Y,
+		Y: v.Y - earthRotationRate*ecefPos.X,
+		Z: v.Z,
+	}
+}
+
+// ComputeLookAngles computes azimuth and elevation in degrees, slant range in kilometers and range rate in kilometers per second
+// from the observer to an eci position and velocity
+func ComputeLookAngles(o Observer, pos satellite.Vector3, vel satellite.Vector3, gmst float64) LookAngles {
+	sat := RotateECIToECEF(pos, gmst)
+	satvel := RotateECIVelocityToECEF(vel, sat, gmst)
+	obs := GeodeticToECEF(o)
+	d := satellite.Vector3{X: sat.X - obs.X, Y: sat.Y - obs.Y, Z: sat.Z - obs.Z}
+
+	look := ComputeLookAnglesECEF(o, sat)
+	look.RangeRate = (d.X*satvel.X + d.Y*satvel.Y + d.Z*satvel.Z) / look.Range
+
+	return look
+}
+
+// ComputeLookAnglesECEF computes azimuth and elevation in degrees and slant range in kilometers from the observer to an earth-fixed position
+func ComputeLookAnglesECEF(o Observer, sat satellite.Vector3) LookAngles {
+	obs := GeodeticToECEF(o)
+	e, n, u := toENU(o, satellite.Vector3{X: sat.X - obs.X, Y: sat.Y - obs.Y, Z: sat.Z - obs.Z})
+
+	rng := math.Sqrt(e*e + n*n + u*u)
+	az := helpers.Rads2Degs(math.Atan2(e, n))
+	if az < 0 {
+		az = az + 360.0
+	}
+
+	return LookAngles{
+		Azimuth:   az,
+		Elevation: helpers.Rads2Degs(math.Asin(u / rng)),
+		Range:     rng,
+	}
+}
+
+// LookAnglesAt propagates the satellite to the given time and returns its look angles as seen by the observer
+func LookAnglesAt(o Observer, sat Orbit, t time.Time) LookAngles {
+	pos, vel, gmst := PropagateSatellite(t, sat)
+	look := ComputeLookAngles(o, pos, vel, gmst)
+	look.Time = t.UTC().Format(time.RFC3339)
+	return look
+}
+
+// ElevationAt propagates the satellite to the given time and returns its elevation in degrees as seen by the observer
+func ElevationAt(o Observer, sat Orbit, t time.Time) float64 {
+	return LookAnglesAt(o, sat, t).Elevation
+}
+
+// GetLookAngles computes the look angles from a TARGETS target to a FLEET satellite at the given time
+func GetLookAngles(targetID string, satID string, t time.Time) (LookAngles, error) {
+	target, ok := GetTargetFeature(targetID)
+	if !ok {
+		return LookAngles{}, fmt.Errorf("target %s not found", targetID)
+	}
+	sat, ok := SatelliteOrbitAt(satID, t)
+	if !ok {
+		return LookAngles{}, fmt.Errorf("satellite %s not found", satID)
+	}
+
+	look := LookAnglesAt(TargetObserver(target), sat, t)
+	look.TargetID = targetID
+	look.SatelliteID = satID
+
+	return look, nil
+}
+
+// toENU projects an earth-fixed vector onto the observer's east, north and up axes
+func toENU(o Observer, d satellite.Vector3) (float64, float64, float64) {
+	lat := helpers.Degs2Rads(o.Latitude)
+	lng := helpers.Degs2Rads(o.Longitude)
+
+	e := -math.Sin(lng)*d.X + math.Cos(lng)*d.Y
+	n := -math.Sin(lat)*math.Cos(lng)*d.X - math.Sin(lat)*math.Sin(lng)*d.Y + math.Cos(lat)*d.Z
+	u := math.Cos(lat)*math.Cos(lng)*d.X + math.Cos(lat)*math.Sin(lng)*d.Y + math.Sin(lat)*d.Z
+
+	return e, n, u
+}
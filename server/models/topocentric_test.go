@@ -0,0 +1,87 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	satellite "github.com/joshuaferrara/go-satellite"
+)
+
+func TestGeodeticToECEF(t *testing.T) {
+	tests := []struct {
+		name string
+		o    Observer
+		want satellite.Vector3
+	}{
+		{"equator at greenwich", Observer{0, 0, 0}, satellite.Vector3{X: wgs84A, Y: 0, Z: 0}},
+		{"equator at 90 east a kilometer up", Observer{0, 90, 1}, satellite.Vector3{X: 0, Y: wgs84A + 1, Z: 0}},
+		{"north pole", Observer{90, 0, 0}, satellite.Vector3{X: 0, Y: 0, Z: 6356.752314245}},
+		{"south pole", Observer{-90, 0, 0}, satellite.Vector3{X: 0, Y: 0, Z: -6356.752314245}},
+		{"45 north 45 west", Observer{45, -45, 0}, satellite.Vector3{X: 3194.419145, Y: -3194.419145, Z: 4487.348409}},
+	}
+
+	for _, tt := range tests {
+		got := GeodeticToECEF(tt.o)
+		if math.Abs(got.X-tt.want.X) > 1e-6 || math.Abs(got.Y-tt.want.Y) > 1e-6 || math.Abs(got.Z-tt.want.Z) > 1e-6 {
+			t.Errorf("%s: GeodeticToECEF = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComputeLookAngles(t *testing.T) {
+	// the observer on the equator at greenwich looks up the x axis, east is y and north is z.
+	// Azimuth is not checked straight overhead, where it is undefined
+	equator := Observer{0, 0, 0}
+	still := satellite.Vector3{}
+	tests := []struct {
+		name      string
+		o         Observer
+		pos       satellite.Vector3
+		vel       satellite.Vector3
+		gmst      float64
+		azimuth   float64
+		elevation float64
+		rng       float64
+		rangeRate float64
+	}{
+		{"overhead receding", equator, satellite.Vector3{X: wgs84A + 1000}, satellite.Vector3{X: 1}, 0,
+			math.NaN(), 90, 1000, 1},
+		{"overhead approaching", equator, satellite.Vector3{X: wgs84A + 1000}, satellite.Vector3{X: -1}, 0,
+			math.NaN(), 90, 1000, -1},
+		{"overhead after a quarter turn of the earth", equator, satellite.Vector3{Y: wgs84A + 1000}, satellite.Vector3{Y: 1}, math.Pi / 2,
+			math.NaN(), 90, 1000, 1},
+		// a satellite still in inertial space on the eastern horizon is approached at the earth's rotation speed
+		{"east on the horizon", equator, satellite.Vector3{X: wgs84A, Y: 1000}, still, 0,
+			90, 0, 1000, -earthRotationRate * wgs84A},
+		{"west on the horizon", equator, satellite.Vector3{X: wgs84A, Y: -1000}, still, 0,
+			270, 0, 1000, earthRotationRate * wgs84A},
+		{"north on the horizon", equator, satellite.Vector3{X: wgs84A, Z: 1000}, still, 0,
+			0, 0, 1000, 0},
+		{"south on the horizon", equator, satellite.Vector3{X: wgs84A, Z: -1000}, still, 0,
+			180, 0, 1000, 0},
+		{"45 degrees up to the east", equator, satellite.Vector3{X: wgs84A + 1000, Y: 1000}, still, 0,
+			90, 45, 1000 * math.Sqrt2, -earthRotationRate * wgs84A / math.Sqrt2},
+		{"north from 90 east", Observer{0, 90, 0}, satellite.Vector3{Y: wgs84A, Z: 1000}, still, 0,
+			0, 0, 1000, 0},
+		{"east from 90 east", Observer{0, 90, 0}, satellite.Vector3{X: -1000, Y: wgs84A}, still, 0,
+			90, 0, 1000, -earthRotationRate * wgs84A},
+		{"over the north pole", Observer{90, 0, 0}, satellite.Vector3{Z: 6356.752314245 + 500}, satellite.Vector3{Z: -2}, 0,
+			math.NaN(), 90, 500, -2},
+	}
+
+	for _, tt := range tests {
+		got := ComputeLookAngles(tt.o, tt.pos, tt.vel, tt.gmst)
+		if !math.IsNaN(tt.azimuth) && math.Abs(got.Azimuth-tt.azimuth) > 1e-9 {
+			t.Errorf("%s: azimuth %g, want %g", tt.name, got.Azimuth, tt.azimuth)
+		}
+		if math.Abs(got.Elevation-tt.elevation) > 1e-9 {
+			t.Errorf("%s: elevation %g, want %g", tt.name, got.Elevation, tt.elevation)
+		}
+		if math.Abs(got.Range-tt.rng) > 1e-6 {
+			t.Errorf("%s: range %g, want %g", tt.name, got.Range, tt.rng)
+		}
+		if math.Abs(got.RangeRate-tt.rangeRate) > 1e-9 {
+			t.Errorf("%s: range rate %g, want %g", tt.name, got.RangeRate, tt.rangeRate)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// VisibleTarget target that a satellite can currently see above the target's AOS elevation
+type VisibleTarget struct {
+	TargetID  string  `json:"targetID"`
+	ShortName string  `json:"shortName"`
+	Azimuth   float64 `json:"azimuth"`
+	Elevation float64 `json:"elevation"`
+	Range     float64 `json:"range"`
+}
+
+// VisibleTargetType graphql object for targets visible from a satellite
+var VisibleTargetType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "VisibleTarget",
+	Fields: graphql.Fields{
+		"targetId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"shortName": &graphql.Field{
+			Type: graphql.String,
+		},
+		"azimuth": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"elevation": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"range": &graphql.Field{
+			Type: graphql.Float,
+		},
+	},
+})
+
+// SatelliteVisibility all targets a satellite can see at a given time
+type SatelliteVisibility struct {
+	SatelliteID string          `json:"satelliteID"`
+	Time        string          `json:"time"`
+	Targets     []VisibleTarget `json:"targets"`
+}
+
+// SatelliteVisibilityType graphql object for a row of the visibility matrix
+var SatelliteVisibilityType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SatelliteVisibility",
+	Fields: graphql.Fields{
+		"satelliteId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"time": &graphql.Field{
+			Type: graphql.String,
+		},
+		"targets": &graphql.Field{
+			Type:        graphql.NewList(VisibleTargetType),
+			Description: "targets above their minElTlmAOS, highest elevation first",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+				s := params.Source.(SatelliteVisibility)
+
+				return s.Targets, nil
+			},
+		},
+	},
+})
+
+// SatelliteObserverPoint converts a satellite feature's sub-satellite point and altitude into an earth-fixed position
+func SatelliteObserverPoint(s SatelliteFeature) Observer {
+	return Observer{
+		Latitude:  s.Geometry.Coordinates[1],
+		Longitude: s.Geometry.Coordinates[0],
+		Altitude:  s.Properties.Altitude,
+	}
+}
+
+// BuildVisibility checks every target against every satellite and keeps the targets above their AOS elevation
+func BuildVisibility(satellites []SatelliteFeature, targets []TargetFeature, t time.Time) []SatelliteVisibility {
+	observers := make([]Observer, 0)
+	for _, target := range targets {
+		observers = append(observers, TargetObserver(target))
+	}
+
+	visibility := make([]SatelliteVisibility, 0)
+	for _, s := range satellites {
+		if len(s.Geometry.Coordinates) < 2 {
+			continue
+		}
+		satpos := GeodeticToECEF(SatelliteObserverPoint(s))
+
+		visible := make([]VisibleTarget, 0)
+		for i, target := range targets {
+			look := ComputeLookAnglesECEF(observers[i], satpos)
+			if look.Elevation >= target.Properties.MinElTlmAOS {
+				visible = append(visible, VisibleTarget{
+					TargetID:  target.Properties.TargetID,
+					ShortName: target.Properties.ShortName,
+					Azimuth:   look.Azimuth,
+					Elevation: look.Elevation,
+					Range:     look.Range,
+				})
+			}
+		}
+		sort.Slice(visible, func(i, j int) bool {
+			return visible[i].Elevation > visible[j].Elevation
+		})
+
+		visibility = append(visibility, SatelliteVisibility{
+			SatelliteID: s.Properties.ID,
+			Time:        t.UTC().Format(time.RFC3339),
+			Targets:     visible,
+		})
+	}
+	sort.Slice(visibility, func(i, j int) bool {
+		return visibility[i].SatelliteID < visibility[j].SatelliteID
+	})
+
+	return visibility
+}
+
+// GetVisibility builds the visibility matrix from the satellites propagated to the given time, so the time each row is
+// labelled with is the time its positions are for
+func GetVisibility(t time.Time) []SatelliteVisibility {
+	return BuildVisibility(GetSatellitesAt(t), GetTargets(), t)
+}
@@ -4,20 +4,21 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/alexmspina/worldmap/server/helpers"
 	"github.com/boltdb/bolt"
 	"github.com/graphql-go/graphql"
+	satellite "github.com/joshuaferrara/go-satellite"
 )
 
 // CatseyeFeature struct modeling geojson polygon struct
 type CatseyeFeature struct {
-	Type       string          `json:"type"`
-	Geometry   PolygonGeometry `json:"geometry"`
-	Properties ZoneProperties  `json:"properties"`
+	Type       string         `json:"type"`
+	Geometry   AreaGeometry   `json:"geometry"`
+	Properties ZoneProperties `json:"properties"`
 }
 
 // CatseyeType graphql object for catseye features
@@ -28,8 +29,8 @@ var CatseyeType = graphql.NewObject(graphql.ObjectConfig{
 			Type: graphql.String,
 		},
 		"geometry": &graphql.Field{
-			Type:        PolyGeoType,
-			Description: "coordinates that build catseye",
+			Type:        AreaGeoType,
+			Description: "polygons that build catseye, split at the antimeridian",
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 
 				s := params.Source.(CatseyeFeature)
@@ -64,6 +65,11 @@ type ZoneProperties struct {
 	CenterLng float64 `json:"centerlng"`
 	EndLng    float64 `json:"endlng"`
 	Gateway   string  `json:"gateway"`
+	// Altitude in kilometers of the satellites serving the zone, 0 to use the fleet's mean tle altitude.
+	// On catseyes it holds the altitude the catseye was drawn for
+	Altitude float64 `json:"altitude,omitempty"`
+	// MinElevation elevation mask in degrees of the zone's gateway, only set on catseyes
+	MinElevation float64 `json:"minElevation,omitempty"`
 }
 
 // ZonePropsType graphql type for target feature properties
@@ -88,108 +94,215 @@ var ZonePropsType = graphql.NewObject(graphql.ObjectConfig{
 		"gateway": &graphql.Field{
 			Type: graphql.String,
 		},
+		"altitude": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "satellite altitude in kilometers the catseye is drawn for",
+		},
+		"minElevation": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "elevation mask in degrees of the zone's gateway",
+		},
 	},
 })
 
-// FillZonesBucket initializes a BoltDB bucket from TARGETS file
-func FillZonesBucket(f string) error {
-	// Open file from filename
-	r := OpenCSV(f)
+// defaultCatseyeAltitude altitude in kilometers catseyes are drawn for when neither the zone nor the fleet gives one
+const defaultCatseyeAltitude = 8062.0
 
-	// Get column header values
-	header := getHeader(r)
-	var err error
+// ReadZonesFile reads the zones of a ZONES file, skipping rows that fail validateZoneRecord
+func ReadZonesFile(f string) ([]ZoneFeature, error) {
+	// the altitude column is optional, so rows may have 6 or 7 columns
+	_, records, err := ReadCSVFile(f, -1)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create list of feature structs
-	for {
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal(err)
+	zones := make([]ZoneFeature, 0)
+	for _, record := range records {
+		if len(record) < 6 {
+			fmt.Println("Skipping zone", record[0], ": expected 6 columns, got", len(record))
+			continue
 		}
-		if record[0] == header[0] {
+		if err := validateZoneRecord(record); err != nil {
+			fmt.Println("Skipping zone", record[1], ":", err)
 			continue
 		}
+		zones = append(zones, buildZoneFeature(record))
+	}
+	return zones, nil
+}
 
-		f := buildZoneFeature(record)
-		id := f.Properties.ZoneID
-
-		featureBytes, err := json.MarshalIndent(f, "", "\t")
+// FillZonesBucket replaces the ZONES bucket with the zones of the given files and the CATSEYES bucket with their
+// catseyes in one transaction. Both are left unchanged when a file cannot be read
+func FillZonesBucket(files []string) error {
+	zones := make(map[string]interface{}, 0)
+	for _, f := range files {
+		features, err := ReadZonesFile(f)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		featureBytes = bytes.Replace(featureBytes, []byte("\\u0026"), []byte("&"), -1)
-		featureBytes = bytes.Trim(featureBytes, "\r")
+		for _, z := range features {
+			zones[z.Properties.ZoneID] = z
+		}
+	}
 
-		err = DB.Update(func(tx *bolt.Tx) error {
-			err = tx.Bucket([]byte("DB")).Bucket([]byte("ZONES")).Put([]byte(id), featureBytes)
-			if err != nil {
-				return fmt.Errorf("could not fill zones bucket: %v", err)
-			}
-			return nil
-		})
+	err := DB.Update(func(tx *bolt.Tx) error {
+		if err := replaceBucket(tx, "ZONES", zones); err != nil {
+			return err
+		}
+		return fillCatseyes(tx)
+	})
+	if err != nil {
+		return err
 	}
 	fmt.Println("Zones bucket filled")
 
-	return err
+	return nil
 }
 
-// FillCatseyesBucket fills bolt db with catseye polygon geojson objects
-func FillCatseyesBucket() {
-	catseyes := make([]CatseyeFeature, 0)
+// fillCatseyes replaces the CATSEYES bucket with the catseye of every zone in the ZONES bucket. It is run again in the
+// same transaction whenever the zones, the fleet's tles or the gateways' elevation masks change, since all shape the catseyes
+func fillCatseyes(tx *bolt.Tx) error {
 	// Get zones from db and calculate coordinates for catseye polygon
-	DB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("DB")).Bucket([]byte("ZONES"))
-		b.ForEach(func(k, v []byte) error {
-			var z ZoneFeature
-			json.Unmarshal(v, &z)
-			centerPoint := []float64{0, z.Properties.CenterLng}
-			endPoint := []float64{0, z.Properties.StartLng}
-			startPoint := []float64{0, z.Properties.EndLng}
-
-			catseyepoints := make([][]float64, 0)
-			ComputeCoverageCircle(endPoint, centerPoint, "end", &catseyepoints)
-			ComputeCoverageCircle(startPoint, centerPoint, "start", &catseyepoints)
-
-			cat := BuildCatseyeFeature(catseyepoints, z.Properties)
-			catseyes = append(catseyes, cat)
-			return nil
-		})
+	catseyes := make(map[string]interface{}, 0)
+	tx.Bucket([]byte("DB")).Bucket([]byte("ZONES")).ForEach(func(k, v []byte) error {
+		var z ZoneFeature
+		json.Unmarshal(v, &z)
+		catseyes[z.Properties.ZoneID] = BuildZoneCatseye(tx, z)
 		return nil
 	})
 
 	// Put new catseye features in db
-	for _, eye := range catseyes {
-		id := eye.Properties.ZoneID
+	return replaceBucket(tx, "CATSEYES", catseyes)
+}
 
-		featureBytes, err := json.MarshalIndent(eye, "", "\t")
-		if err != nil {
-			panic(err)
+// BuildZoneCatseye computes the catseye polygon covering a zone from its start, center and end longitudes: the ground
+// seeing a satellite at the zone's altitude above the gateway's elevation mask from every longitude of the zone
+func BuildZoneCatseye(tx *bolt.Tx, z ZoneFeature) CatseyeFeature {
+	props := z.Properties
+	props.Altitude, props.MinElevation = zoneCoverage(tx, z)
+
+	// unwrap the center and end east of the start so the ring runs continuously across the antimeridian
+	start, center, end := props.StartLng, props.CenterLng, props.EndLng
+	if center < start {
+		center = center + 360.0
+	}
+	if end < start {
+		end = end + 360.0
+	}
+
+	eastEdge := ComputeCoverageCircle(0, start, props.Altitude, props.MinElevation, center, "end")
+	westEdge := ComputeCoverageCircle(0, end, props.Altitude, props.MinElevation, center, "start")
+	if len(eastEdge) == 0 || len(westEdge) == 0 {
+		// the coverage at the zone's edges does not overlap, so no ground sees the satellite across the whole zone
+		fmt.Println("Zone", props.ZoneID, "is too wide to have a catseye at", props.Altitude, "km above", props.MinElevation, "degrees")
+		return BuildCatseyeFeature(NewAreaGeometry([][][][]float64{}), props)
+	}
+
+	return BuildCatseyeFeature(BuildAreaGeometry(append(eastEdge, westEdge...)), props)
+}
+
+// zoneCoverage altitude in kilometers and elevation mask in degrees a zone's catseye is drawn for. The altitude is the
+// zone's own, else the mean tle altitude of the FLEET satellites, and the mask is that of the zone's gateway in TARGETS
+func zoneCoverage(tx *bolt.Tx, z ZoneFeature) (float64, float64) {
+	altitude := z.Properties.Altitude
+	if altitude <= 0 {
+		altitude = defaultCatseyeAltitude
+		if mean, ok := fleetMeanAltitude(tx); ok {
+			altitude = mean
 		}
-		featureBytes = bytes.Replace(featureBytes, []byte("\\u0026"), []byte("&"), -1)
-		featureBytes = bytes.Trim(featureBytes, "\r")
+	}
+
+	return altitude, gatewayMinElevation(tx, z.Properties.Gateway)
+}
 
-		err = DB.Update(func(tx *bolt.Tx) error {
-			err = tx.Bucket([]byte("DB")).Bucket([]byte("CATSEYES")).Put([]byte(id), featureBytes)
-			if err != nil {
-				return fmt.Errorf("could not fill catseyes bucket: %v", err)
+// fleetMeanAltitude mean of the tle altitudes of the satellites in the FLEET bucket, false when none can be read
+func fleetMeanAltitude(tx *bolt.Tx) (float64, bool) {
+	total := 0.0
+	count := 0
+	tx.Bucket([]byte("DB")).Bucket([]byte("FLEET")).ForEach(func(k, v []byte) error {
+		var satstate SatelliteState
+		json.Unmarshal(v, &satstate)
+		altitude, err := TLEMeanAltitude(satstate.TLELine2)
+		if err == nil {
+			total = total + altitude
+			count++
+		}
+		return nil
+	})
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// gatewayMinElevation elevation mask of the TARGETS target a zone's gateway column names by id or short name, 0 when
+// there is no such target
+func gatewayMinElevation(tx *bolt.Tx, gateway string) float64 {
+	gateway = strings.TrimSpace(gateway)
+	if gateway == "" {
+		return 0
+	}
+	minElevation := 0.0
+	tx.Bucket([]byte("DB")).Bucket([]byte("TARGETS")).ForEach(func(k, v []byte) error {
+		var t TargetFeature
+		json.Unmarshal(v, &t)
+		if t.Properties.TargetID == gateway || strings.EqualFold(t.Properties.ShortName, gateway) {
+			minElevation = t.Properties.MinElGateway
+		}
+		return nil
+	})
+	return minElevation
+}
+
+// migrateCatseyesGeoJSON rewrites version 1 catseyes, open rings of lat/lng pairs, as closed lng/lat Polygon or
+// MultiPolygon geometry rebuilt from their zones. Catseyes whose zone no longer exists are dropped
+func migrateCatseyesGeoJSON(tx *bolt.Tx) error {
+	root := tx.Bucket([]byte("DB"))
+	ids := make([]string, 0)
+	root.Bucket([]byte("CATSEYES")).ForEach(func(k, v []byte) error {
+		ids = append(ids, string(k))
+		return nil
+	})
+
+	for _, id := range ids {
+		zone := root.Bucket([]byte("ZONES")).Get([]byte(id))
+		if zone == nil {
+			if err := root.Bucket([]byte("CATSEYES")).Delete([]byte(id)); err != nil {
+				return err
 			}
-			return nil
-		})
+			continue
+		}
+		var z ZoneFeature
+		if err := json.Unmarshal(zone, &z); err != nil {
+			return fmt.Errorf("zone %s: %v", id, err)
+		}
+		if err := putFeature(tx, "CATSEYES", id, BuildZoneCatseye(tx, z)); err != nil {
+			return err
+		}
 	}
-	fmt.Println("Catseyes bucket filled")
+	return nil
 }
 
-// BuildCatseyeFeature creates a catseye struct
-func BuildCatseyeFeature(c [][]float64, p ZoneProperties) CatseyeFeature {
-	g := PolygonGeometry{
-		"Polygon",
-		c,
+// putFeature writes a feature as indented json to a bucket under the DB root bucket
+func putFeature(tx *bolt.Tx, bucket string, id string, feature interface{}) error {
+	featureBytes, err := json.MarshalIndent(feature, "", "\t")
+	if err != nil {
+		return err
 	}
+	featureBytes = bytes.Replace(featureBytes, []byte("\\u0026"), []byte("&"), -1)
+	featureBytes = bytes.Trim(featureBytes, "\r")
+
+	err = tx.Bucket([]byte("DB")).Bucket([]byte(bucket)).Put([]byte(id), featureBytes)
+	if err != nil {
+		return fmt.Errorf("could not fill %s bucket: %v", strings.ToLower(bucket), err)
+	}
+	return nil
+}
+
+// BuildCatseyeFeature creates a catseye struct
+func BuildCatseyeFeature(g AreaGeometry, p ZoneProperties) CatseyeFeature {
 	v := CatseyeFeature{
-		"Catseye",
+		"Feature",
 		g,
 		p,
 	}
@@ -228,6 +341,9 @@ func buildZoneFeature(r []string) ZoneFeature {
 		EndLng:    EndLng,
 		Gateway:   r[5],
 	}
+	if len(r) > 6 {
+		props.Altitude = helpers.ConvertStringToFloat64(r[6])
+	}
 	f := ZoneFeature{
 		"Zone",
 		props,
@@ -235,74 +351,127 @@ func buildZoneFeature(r []string) ZoneFeature {
 	return f
 }
 
-// ComputeCoverageCircle generate list of lat/lng points
-func ComputeCoverageCircle(p []float64, c []float64, s string, l *[][]float64) {
-	elevation := helpers.Degs2Rads(0)
-	height := 8062000.0
-	earthRadius := 6378000.0
-	subSatLat := helpers.Degs2Rads(p[0])
-	subSatLng := helpers.Degs2Rads(p[1])
-	centralAngle := math.Acos(math.Cos(elevation)/(1+height/earthRadius)) - elevation
+// ComputeCoverageCircle generates lng/lat points one degree of azimuth apart on the edge of the ground that sees a
+// satellite at altitude kilometers above the sub-satellite point at or above minElevation degrees, keeping those east
+// of centerLng for side "end", west of it for "start" and all of them for "full". Longitudes stay continuous with lng,
+// so they may run past ±180
+func ComputeCoverageCircle(lat float64, lng float64, altitude float64, minElevation float64, centerLng float64, side string) [][]float64 {
+	sat := GeodeticToECEF(Observer{Latitude: lat, Longitude: lng, Altitude: altitude})
 
+	points := make([][]float64, 0)
 	for i := 0; i < 360; i++ {
-		j := float64(i)
-		lat := helpers.Rads2Degs(math.Asin(math.Sin(subSatLat)*math.Cos(centralAngle) + math.Cos(subSatLat)*math.Sin(centralAngle)*math.Cos(j)))
-		lng := helpers.Rads2Degs(subSatLng + math.Atan2(math.Sin(j)*math.Sin(centralAngle)*math.Cos(subSatLat), math.Cos(centralAngle)-math.Sin(subSatLat)*math.Sin(math.Asin(math.Sin(subSatLat)*math.Cos(centralAngle)+math.Cos(subSatLat)*math.Sin(centralAngle)*math.Cos(j)))))
-		point := []float64{
-			lat,
-			lng,
-		}
+		point := coverageEdge(sat, lat, lng, float64(i), minElevation)
 
-		switch s {
+		switch side {
 		case "full":
-			*l = append(*l, point)
+			points = append(points, point)
 		case "start":
-			if lng < c[1] {
-				*l = append(*l, point)
+			if point[0] < centerLng {
+				points = append(points, point)
 			}
 		case "end":
-			if lng > c[1] {
-				*l = append(*l, point)
+			if point[0] > centerLng {
+				points = append(points, point)
 			}
-		default:
-			continue
 		}
 	}
+	return points
+}
+
+// coverageEdge walks from the sub-satellite point along an azimuth in degrees and finds by bisection the lng/lat point on
+// the WGS84 ellipsoid where the earth-fixed satellite is seen at minElevation degrees above the local horizon
+func coverageEdge(sat satellite.Vector3, lat float64, lng float64, azimuth float64, minElevation float64) []float64 {
+	subSatLat := helpers.Degs2Rads(lat)
+	az := helpers.Degs2Rads(azimuth)
+	destination := func(angle float64) Observer {
+		pointLat := math.Asin(math.Sin(subSatLat)*math.Cos(angle) + math.Cos(subSatLat)*math.Sin(angle)*math.Cos(az))
+		pointLng := math.Atan2(math.Sin(az)*math.Sin(angle)*math.Cos(subSatLat), math.Cos(angle)-math.Sin(subSatLat)*math.Sin(pointLat))
+		return Observer{Latitude: helpers.Rads2Degs(pointLat), Longitude: lng + helpers.Rads2Degs(pointLng)}
+	}
+
+	// elevation falls from 90 degrees under the satellite to below the horizon a quarter of the earth away
+	near, far := 0.0, math.Pi/2
+	for i := 0; i < 40; i++ {
+		mid := (near + far) / 2
+		if ComputeLookAnglesECEF(destination(mid), sat).Elevation > minElevation {
+			near = mid
+		} else {
+			far = mid
+		}
+	}
+	edge := destination((near + far) / 2)
+
+	return []float64{edge.Longitude, edge.Latitude}
+}
+
+// CoverageCentralAngle earth central angle in radians between the sub-satellite point and the edge of coverage
+// for a satellite at height above a spherical earth, seen at the given elevation in radians
+func CoverageCentralAngle(elevation float64, height float64, earthRadius float64) float64 {
+	return math.Acos(math.Cos(elevation)/(1+height/earthRadius)) - elevation
 }
 
 // GetCurrentZone determine which zone the satellite is currently servicing
 func GetCurrentZone(satlng float64) []string {
 	var zoneid []string
-	err := DB.Batch(func(tx *bolt.Tx) error {
+	for _, zone := range GetZones() {
+		if ZoneContainsLng(zone, satlng) {
+			zoneid = append(zoneid, zone.Properties.ZoneID)
+		}
+	}
+	return zoneid
+}
+
+// ZoneContainsLng reports whether a longitude falls strictly between a zone's start and end longitudes. A zone wrapping
+// the antimeridian only matches negative longitudes, the matching GetCurrentZone has always used for live missions
+func ZoneContainsLng(zone ZoneFeature, lng float64) bool {
+	zonestartlng := zone.Properties.StartLng
+	zoneendlng := zone.Properties.EndLng
+
+	// shift longitudes less than 0 to 0 - 360 range for easy zone placement
+	if zoneendlng < zonestartlng {
+		zoneendlng = zoneendlng + 360.0
+
+		if lng < 0 {
+			lngadjusted := lng + 360.0
+			return lngadjusted > zonestartlng && lngadjusted < zoneendlng
+		}
+		return false
+	}
+	return lng > zonestartlng && lng < zoneendlng
+}
+
+// GetZones pulls every zone from the ZONES bucket
+func GetZones() []ZoneFeature {
+	zones := make([]ZoneFeature, 0)
+	err := DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("DB")).Bucket([]byte("ZONES"))
 		b.ForEach(func(k, v []byte) error {
 			var zone ZoneFeature
 			json.Unmarshal(v, &zone)
-			zonestartlng := zone.Properties.StartLng
-			zoneendlng := zone.Properties.EndLng
-
-			// shift longitudes less than 0 to 0 - 360 range for easy zone placement
-			if zoneendlng < zonestartlng {
-				zoneendlng = zoneendlng + 360.0
-
-				if satlng < 0 {
-					satlngadjusted := satlng + 360.0
-
-					if satlngadjusted > zonestartlng && satlngadjusted < zoneendlng {
-						zoneid = append(zoneid, string(k))
-					}
-				}
-			} else {
-				if satlng > zonestartlng && satlng < zoneendlng {
-					zoneid = append(zoneid, string(k))
-				}
-			}
+			zones = append(zones, zone)
 			return nil
 		})
 		return nil
 	})
 	helpers.PanicErrors(err)
-	return zoneid
+	return zones
+}
+
+// GetCatseyes pulls every catseye from the CATSEYES bucket
+func GetCatseyes() []CatseyeFeature {
+	catseyes := make([]CatseyeFeature, 0)
+	err := DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("DB")).Bucket([]byte("CATSEYES"))
+		b.ForEach(func(k, v []byte) error {
+			var catseye CatseyeFeature
+			json.Unmarshal(v, &catseye)
+			catseyes = append(catseyes, catseye)
+			return nil
+		})
+		return nil
+	})
+	helpers.PanicErrors(err)
+	return catseyes
 }
 
 // GetCatseye queries bolt db for the desired target
@@ -319,3 +488,178 @@ func GetCatseye(s string) CatseyeFeature {
 
 	return catseyefeature
 }
+
+// zoneColumns columns of a ZONES file row in the order buildZoneFeature reads them
+var zoneColumns = []string{"subregion", "id", "startLng", "centerLng", "endLng", "gateway", "altitude"}
+
+// zoneRecord converts a zone feature back into a ZONES file row
+func zoneRecord(z ZoneFeature) []string {
+	return []string{
+		z.Properties.Subregion,
+		z.Properties.ZoneID,
+		strconv.FormatFloat(z.Properties.StartLng, 'f', -1, 64),
+		strconv.FormatFloat(z.Properties.CenterLng, 'f', -1, 64),
+		strconv.FormatFloat(z.Properties.EndLng, 'f', -1, 64),
+		z.Properties.Gateway,
+		zoneAltitudeColumn(z.Properties.Altitude),
+	}
+}
+
+// zoneAltitudeColumn writes an unset zone altitude as an empty column
+func zoneAltitudeColumn(altitude float64) string {
+	if altitude <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(altitude, 'f', -1, 64)
+}
+
+// validateZoneRecord checks a ZONES row before buildZoneFeature converts it. Longitudes may run past 180
+// as overLngWindow expects, and going east from the start longitude the center must come before the end
+func validateZoneRecord(r []string) error {
+	if r[1] == "" {
+		return fmt.Errorf("zone id is required")
+	}
+	lngs := make([]float64, 0)
+	for i := 2; i <= 4; i++ {
+		lng, err := strconv.ParseFloat(r[i], 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", zoneColumns[i], r[i])
+		}
+		if lng < -180 || lng > 360 {
+			return fmt.Errorf("%s must be between -180 and 360 degrees", zoneColumns[i])
+		}
+		lngs = append(lngs, lng)
+	}
+	if len(r) > 6 && r[6] != "" {
+		altitude, err := strconv.ParseFloat(r[6], 64)
+		if err != nil || altitude <= 0 {
+			return fmt.Errorf("altitude must be a positive number of kilometers, got %q", r[6])
+		}
+	}
+
+	// unwrap the center and end east of the start the same way GetCurrentZone places satellites
+	lngs = overLngWindow(lngs...)
+	start, center, end := lngs[0], lngs[1], lngs[2]
+	if center < start {
+		center = center + 360.0
+	}
+	if end < start {
+		end = end + 360.0
+	}
+	if !(start < center && center < end) {
+		return fmt.Errorf("zone %s longitudes must run east from startLng through centerLng to endLng", r[1])
+	}
+
+	return nil
+}
+
+// GetZone queries bolt db for the desired zone and reports whether it exists
+func GetZone(s string) (ZoneFeature, bool) {
+	var zonefeature ZoneFeature
+	found := false
+	err := DB.View(func(tx *bolt.Tx) error {
+		zonefeature, found = zoneInTx(tx, s)
+		return nil
+	})
+	helpers.PanicErrors(err)
+
+	return zonefeature, found
+}
+
+// zoneInTx reads a zone from the ZONES bucket within a transaction
+func zoneInTx(tx *bolt.Tx, id string) (ZoneFeature, bool) {
+	var zonefeature ZoneFeature
+	zone := tx.Bucket([]byte("DB")).Bucket([]byte("ZONES")).Get([]byte(id))
+	if zone == nil {
+		return zonefeature, false
+	}
+	json.Unmarshal(zone, &zonefeature)
+
+	return zonefeature, true
+}
+
+// storeZone writes a zone and its recomputed catseye to the ZONES and CATSEYES buckets within a transaction
+func storeZone(tx *bolt.Tx, r []string) (CatseyeFeature, error) {
+	z := buildZoneFeature(r)
+	if err := putFeature(tx, "ZONES", z.Properties.ZoneID, z); err != nil {
+		return CatseyeFeature{}, err
+	}
+	eye := BuildZoneCatseye(tx, z)
+
+	return eye, putFeature(tx, "CATSEYES", z.Properties.ZoneID, eye)
+}
+
+// CreateZone validates a ZONES row, adds it to the ZONES bucket and computes its catseye
+func CreateZone(r []string) (CatseyeFeature, error) {
+	if err := validateZoneRecord(r); err != nil {
+		return CatseyeFeature{}, err
+	}
+
+	var eye CatseyeFeature
+	err := DB.Update(func(tx *bolt.Tx) error {
+		if _, ok := zoneInTx(tx, r[1]); ok {
+			return fmt.Errorf("zone %s already exists", r[1])
+		}
+		var err error
+		eye, err = storeZone(tx, r)
+		return err
+	})
+	if err != nil {
+		return CatseyeFeature{}, err
+	}
+
+	return eye, nil
+}
+
+// UpdateZone replaces the columns of an existing zone that are set in changes, keyed by zoneColumns name,
+// and recomputes its catseye
+func UpdateZone(id string, changes map[string]string) (CatseyeFeature, error) {
+	var eye CatseyeFeature
+	err := DB.Update(func(tx *bolt.Tx) error {
+		existing, ok := zoneInTx(tx, id)
+		if !ok {
+			return fmt.Errorf("zone %s not found", id)
+		}
+
+		r := zoneRecord(existing)
+		for i, column := range zoneColumns {
+			if value, isSet := changes[column]; isSet && column != "id" {
+				r[i] = value
+			}
+		}
+		if err := validateZoneRecord(r); err != nil {
+			return err
+		}
+
+		var err error
+		eye, err = storeZone(tx, r)
+		return err
+	})
+	if err != nil {
+		return CatseyeFeature{}, err
+	}
+
+	return eye, nil
+}
+
+// DeleteZone removes a zone and its catseye from the ZONES and CATSEYES buckets and returns the catseye
+func DeleteZone(id string) (CatseyeFeature, error) {
+	var eye CatseyeFeature
+	err := DB.Update(func(tx *bolt.Tx) error {
+		if _, ok := zoneInTx(tx, id); !ok {
+			return fmt.Errorf("zone %s not found", id)
+		}
+		catseyes := tx.Bucket([]byte("DB")).Bucket([]byte("CATSEYES"))
+		json.Unmarshal(catseyes.Get([]byte(id)), &eye)
+
+		if err := tx.Bucket([]byte("DB")).Bucket([]byte("ZONES")).Delete([]byte(id)); err != nil {
+			return err
+		}
+		return catseyes.Delete([]byte(id))
+	})
+	if err != nil {
+		return CatseyeFeature{}, err
+	}
+
+	return eye, nil
+}